@@ -8,35 +8,74 @@ package main
 
 import (
 	_ "TestRest/docs"
+	"TestRest/external"
 	"TestRest/internal/config"
 	"TestRest/internal/handlers"
+	"TestRest/internal/health"
+	"TestRest/internal/jobs"
+	"TestRest/internal/metrics"
+	"TestRest/internal/tracing"
 	"TestRest/pkg/logger"
 	"TestRest/pkg/migrations"
 	"TestRest/pkg/postgres"
 	"context"
+	"flag"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/swaggo/http-swagger"
 	"go.uber.org/zap"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+const migrationsPath = "./pkg/migrations"
+
+// tracingFlushTimeout bounds the export of the spans still buffered at exit.
+const tracingFlushTimeout = 5 * time.Second
+
 func main() {
-	ctx := context.Background()
-	ctx, err := logger.New(ctx)
+	configPath := flag.String("config", "", "path of a YAML config file; environment variables that are set override it")
+	printConfig := flag.Bool("print-config", false, "print the effective config with secrets masked and exit")
+	flag.Parse()
 
-	cfg, err := config.New()
+	// The logger is configured from the config, so config errors can only go to stderr.
+	cfg, err := config.New(*configPath)
 	if err != nil {
-		logger.GetLoggerFromContext(ctx).Fatal(ctx, "failed to load config", zap.Error(err))
+		fmt.Fprintln(os.Stderr, "failed to load config:", err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		if err = cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to print config:", err)
+			os.Exit(1)
+		}
+		return
 	}
-	logger.GetLoggerFromContext(ctx).Info(ctx, "Config loaded")
 
+	ctx, err := logger.New(context.Background(), cfg.Logger)
 	if err != nil {
-		logger.GetLoggerFromContext(ctx).Fatal(ctx, "failed to create logger", zap.Error(err))
+		fmt.Fprintln(os.Stderr, "failed to create logger:", err)
+		os.Exit(1)
+	}
+	defer logger.GetLoggerFromContext(ctx).Sync()
+
+	// ctx is cancelled by the first SIGINT or SIGTERM; a second one kills the process as usual.
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Config loaded", zap.String("log_level", cfg.Logger.Level))
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		logger.GetLoggerFromContext(ctx).Fatal(ctx, "failed to set up tracing", zap.Error(err))
 		return
 	}
-	logger.GetLoggerFromContext(ctx).Info(ctx, "Middleware initialized")
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Tracing initialized", zap.Bool("export", cfg.Tracing.Enabled))
 
 	db, err := postgres.New(ctx, cfg.Postgres)
 	if err != nil {
@@ -50,31 +89,165 @@ func main() {
 	logger.GetLoggerFromContext(ctx).Info(ctx, "Database connected")
 	defer db.Close()
 
-	if err = migrations.RunMigrations(db, "./pkg/migrations", *cfg); err != nil {
+	if err = migrations.RunMigrations(db, migrationsPath, *cfg); err != nil {
 		logger.GetLoggerFromContext(ctx).Fatal(ctx, "failed to run migrations", zap.Error(err))
 		return
 	}
-	logger.GetLoggerFromContext(ctx).Info(ctx, "Migrations applied")
+	schemaVersion, err := migrations.LatestVersion(migrationsPath)
+	if err != nil {
+		logger.GetLoggerFromContext(ctx).Fatal(ctx, "failed to read migrations", zap.Error(err))
+		return
+	}
+	metrics.SchemaVersion.Set(float64(schemaVersion))
+	prometheus.MustRegister(metrics.NewPoolCollector(db))
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Migrations applied", zap.Uint("version", schemaVersion))
+
+	provider, err := external.New(cfg.ExternalAPIs, &http.Client{})
+	if err != nil {
+		logger.GetLoggerFromContext(ctx).Fatal(ctx, "failed to create enrichment provider", zap.Error(err))
+		return
+	}
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Enrichment provider initialized", zap.String("provider", cfg.ExternalAPIs.Provider))
+
+	enricher := provider
 
-	handlers.InitHandlers(db, ctx)
+	if batchEnricher, ok := enricher.(external.BatchEnricher); ok && cfg.ExternalAPIs.BatchEnabled {
+		enricher = external.NewBatchingEnricher(batchEnricher, cfg.ExternalAPIs)
+		logger.GetLoggerFromContext(ctx).Info(ctx, "Enrichment batching enabled", zap.Duration("window", cfg.ExternalAPIs.BatchWindow))
+	}
+
+	if cfg.ExternalAPIs.CacheEnabled {
+		enricher = external.NewCachedEnricher(enricher, postgres.NewEnrichmentCache(db), cfg.ExternalAPIs)
+		logger.GetLoggerFromContext(ctx).Info(ctx, "Enrichment cache enabled")
+	}
+
+	enrichment, err := external.NewClient(enricher, cfg.ExternalAPIs)
+	if err != nil {
+		logger.GetLoggerFromContext(ctx).Fatal(ctx, "failed to create enrichment client", zap.Error(err))
+		return
+	}
+
+	people := postgres.NewRepository(db)
+	h := handlers.New(people, enrichment, cfg.RequireIfMatch)
 	logger.GetLoggerFromContext(ctx).Info(ctx, "Handlers initialized")
 
+	if cfg.TrashRetention > 0 {
+		go jobs.NewPurger(people, cfg.TrashRetention, cfg.TrashPurgeInterval).Run(ctx)
+		logger.GetLoggerFromContext(ctx).Info(ctx, "Trash purge started", zap.Duration("retention", cfg.TrashRetention))
+	}
+
+	var readiness health.Readiness
+	checks := []health.Check{
+		{Name: "postgres", Run: db.Ping},
+		{Name: "migrations", Run: func(ctx context.Context) error {
+			version, dirty, err := migrations.CurrentVersion(ctx, db)
+			if err != nil {
+				return err
+			}
+			if dirty || version != schemaVersion {
+				return fmt.Errorf("database is at version %d (dirty: %t), expected %d", version, dirty, schemaVersion)
+			}
+			return nil
+		}},
+	}
+	if pinger, ok := provider.(external.Pinger); ok && cfg.Health.CheckEnrichment {
+		// Lookups only fail requests under the "fail" policy; otherwise an unreachable provider is not fatal.
+		checks = append(checks, health.Check{
+			Name:     "enrichment",
+			Optional: cfg.ExternalAPIs.FailurePolicy != external.PolicyFail,
+			Run:      pinger.Ping,
+		})
+	}
+	checker := health.NewChecker(cfg.Health, &readiness, checks...)
+
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(metrics.Middleware)
+	router.Use(handlers.Audit)
 	router.Use(logger.Middleware(ctx))
-	router.Use(middleware.Recoverer)
+	router.Use(handlers.Recoverer)
 	router.Use(middleware.URLFormat)
 
+	router.NotFound(handlers.NotFound)
+	router.MethodNotAllowed(handlers.MethodNotAllowed)
+
 	router.Get("/swagger/*", httpSwagger.WrapHandler)
+	router.Get("/healthz", health.Healthz)
+	router.Get("/readyz", checker.Readyz)
+	router.Handle("/metrics", metrics.Handler())
+	if cfg.Logger.LevelEndpoint {
+		router.Method(http.MethodGet, "/admin/log-level", logger.GetLoggerFromContext(ctx).LevelHandler())
+		router.Method(http.MethodPut, "/admin/log-level", logger.GetLoggerFromContext(ctx).LevelHandler())
+	}
+
+	router.Route("/people", func(r chi.Router) {
+		r.Get("/", h.ListPeople)
+		r.Post("/", h.CreatePerson)
+		r.Get("/trash", h.ListTrash)
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", h.GetPerson)
+			r.Put("/", h.UpdatePerson)
+			r.Patch("/", h.PatchPerson)
+			r.Delete("/", h.DeletePerson)
+			r.Post("/restore", h.RestorePerson)
+			r.Get("/history", h.GetPersonHistory)
+			r.Post("/revert", h.RevertPerson)
+		})
+	})
 
-	router.Get("/get", handlers.GetInfo)
-	router.Delete("/delete", handlers.DeletePerson)
-	router.Post("/post", handlers.InsertPerson)
-	router.Put("/put", handlers.UpdatePerson)
+	router.Post("/people:import", h.ImportPeople)
+	router.With(middleware.Compress(5, "text/csv", "text/tab-separated-values", "application/x-ndjson")).
+		Get("/people:export", h.ExportPeople)
 
-	if err = http.ListenAndServe(fmt.Sprintf("%s:%d", cfg.RESTHost, cfg.RESTPort), router); err != nil {
+	if cfg.LegacyRoutes {
+		router.Get("/get", h.LegacyGetInfo)
+		router.Delete("/delete", h.LegacyDeletePerson)
+		router.Post("/post", h.LegacyInsertPerson)
+		router.Put("/put", h.LegacyUpdatePerson)
+		logger.GetLoggerFromContext(ctx).Info(ctx, "Legacy routes enabled")
+	}
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", cfg.RESTHost, cfg.RESTPort),
+		Handler:           router,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	readiness.Set(true)
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Server started", zap.String("addr", server.Addr))
+
+	select {
+	case err = <-serverErr:
 		logger.GetLoggerFromContext(ctx).Fatal(ctx, "failed to start server", zap.Error(err))
 		return
+	case <-ctx.Done():
+	}
+
+	readiness.Set(false)
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Shutting down", zap.Duration("grace_period", cfg.ShutdownTimeout))
+
+	// In-flight requests keep their own contexts; they get until the grace period ends to finish.
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.ShutdownTimeout)
+	defer cancel()
+	if err = server.Shutdown(shutdownCtx); err != nil {
+		logger.GetLoggerFromContext(ctx).Warn(ctx, "Server did not drain in time", zap.Error(err))
+		server.Close()
+	}
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Server stopped")
+
+	// The grace period may be used up by draining, so flushing the spans of the last requests gets its own.
+	flushCtx, cancelFlush := context.WithTimeout(context.WithoutCancel(ctx), tracingFlushTimeout)
+	defer cancelFlush()
+	if err = shutdownTracing(flushCtx); err != nil {
+		logger.GetLoggerFromContext(ctx).Warn(ctx, "Failed to flush traces", zap.Error(err))
 	}
 }
@@ -0,0 +1,176 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"time"
+)
+
+// MaxBatchSize is the largest number of names agify, genderize and nationalize accept in one request.
+const MaxBatchSize = 10
+
+// BatchEnricher looks up several names at once. Results are in the order of names; a nil result means the provider
+// has no data for that name.
+type BatchEnricher interface {
+	AgeBatch(ctx context.Context, names []string) ([]*Age, error)
+	GenderBatch(ctx context.Context, names []string) ([]*Gender, error)
+	NationalityBatch(ctx context.Context, names []string) ([]*Nationality, error)
+}
+
+// BatchingEnricher coalesces single-name lookups made within a short window, e.g. by concurrent inserts or a bulk
+// import, into batch requests of up to MaxBatchSize names and fans the results back out to each caller.
+type BatchingEnricher struct {
+	age         *batcher[Age]
+	gender      *batcher[Gender]
+	nationality *batcher[Nationality]
+}
+
+var _ Enricher = (*BatchingEnricher)(nil)
+
+func NewBatchingEnricher(next BatchEnricher, cfg Config) *BatchingEnricher {
+	return &BatchingEnricher{
+		age:         newBatcher(next.AgeBatch, cfg.BatchWindow, cfg.Timeout),
+		gender:      newBatcher(next.GenderBatch, cfg.BatchWindow, cfg.Timeout),
+		nationality: newBatcher(next.NationalityBatch, cfg.BatchWindow, cfg.Timeout),
+	}
+}
+
+func (e *BatchingEnricher) Age(ctx context.Context, name string) (Age, error) {
+	return e.age.do(ctx, name)
+}
+
+func (e *BatchingEnricher) Gender(ctx context.Context, name string) (Gender, error) {
+	return e.gender.do(ctx, name)
+}
+
+func (e *BatchingEnricher) Nationality(ctx context.Context, name string) (Nationality, error) {
+	return e.nationality.do(ctx, name)
+}
+
+// batcher collects calls for one kind of lookup. A batch is sent when it is full or when window has passed since
+// its first call, whichever comes first.
+type batcher[T any] struct {
+	fetch   func(ctx context.Context, names []string) ([]*T, error)
+	window  time.Duration
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending []*batchCall[T]
+	timer   *time.Timer
+}
+
+type batchCall[T any] struct {
+	name  string
+	span  trace.SpanContext
+	done  chan struct{}
+	value *T
+	err   error
+}
+
+func newBatcher[T any](fetch func(context.Context, []string) ([]*T, error), window, timeout time.Duration) *batcher[T] {
+	return &batcher[T]{fetch: fetch, window: window, timeout: timeout}
+}
+
+func (b *batcher[T]) do(ctx context.Context, name string) (T, error) {
+	call := &batchCall[T]{name: name, span: trace.SpanContextFromContext(ctx), done: make(chan struct{})}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, call)
+	var full []*batchCall[T]
+	switch {
+	case len(b.pending) >= MaxBatchSize:
+		full = b.take()
+	case len(b.pending) == 1:
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+	b.mu.Unlock()
+
+	if full != nil {
+		go b.send(full)
+	}
+
+	var zero T
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+	if call.err != nil {
+		return zero, call.err
+	}
+	if call.value == nil {
+		return zero, ErrNoData
+	}
+	return *call.value, nil
+}
+
+// take removes the pending calls. b.mu must be held.
+func (b *batcher[T]) take() []*batchCall[T] {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch := b.pending
+	b.pending = nil
+	return batch
+}
+
+func (b *batcher[T]) flush() {
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+
+	if len(batch) > 0 {
+		b.send(batch)
+	}
+}
+
+// send looks up the distinct names of batch in one request and completes every call. The request is traced in a
+// span of its own, linked to the span of every caller.
+func (b *batcher[T]) send(batch []*batchCall[T]) {
+	// The batch serves several callers, so it must not be cancelled with any one of their contexts.
+	ctx := context.Background()
+	if b.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.timeout)
+		defer cancel()
+	}
+
+	var links []trace.Link
+	for _, call := range batch {
+		if call.span.IsValid() {
+			links = append(links, trace.Link{SpanContext: call.span})
+		}
+	}
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "enrichment batch",
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("enrichment.batch_size", len(batch))),
+	)
+	defer span.End()
+
+	index := map[string]int{}
+	var names []string
+	for _, call := range batch {
+		if _, ok := index[call.name]; !ok {
+			index[call.name] = len(names)
+			names = append(names, call.name)
+		}
+	}
+
+	values, err := b.fetch(ctx, names)
+	if err == nil && len(values) != len(names) {
+		err = fmt.Errorf("batch lookup returned %d results for %d names", len(values), len(names))
+	}
+	for _, call := range batch {
+		if err != nil {
+			call.err = err
+		} else {
+			call.value = values[index[call.name]]
+		}
+		close(call.done)
+	}
+}
@@ -0,0 +1,138 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingFetch is a batch lookup that answers each name with its length and records the batches it was sent.
+type recordingFetch struct {
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+	missing map[string]bool
+}
+
+func (f *recordingFetch) fetch(_ context.Context, names []string) ([]*int, error) {
+	f.mu.Lock()
+	f.batches = append(f.batches, append([]string(nil), names...))
+	f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	results := make([]*int, len(names))
+	for i, name := range names {
+		if !f.missing[name] {
+			n := len(name)
+			results[i] = &n
+		}
+	}
+	return results, nil
+}
+
+func (f *recordingFetch) sent() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.batches
+}
+
+// lookupAll calls b.do for every name concurrently and returns the results in the order of names.
+func lookupAll(ctx context.Context, b *batcher[int], names []string) ([]int, []error) {
+	values := make([]int, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values[i], errs[i] = b.do(ctx, name)
+		}()
+	}
+	wg.Wait()
+	return values, errs
+}
+
+func TestBatcher(t *testing.T) {
+	fetchErr := errors.New("provider down")
+
+	tests := []struct {
+		name        string
+		names       []string
+		window      time.Duration
+		fetchErr    error
+		missing     map[string]bool
+		wantBatches int
+		wantSent    int
+	}{
+		{name: "window flush", names: []string{"ann", "bob", "carl"}, window: 20 * time.Millisecond, wantBatches: 1, wantSent: 3},
+		{name: "duplicates sent once", names: []string{"ann", "ann", "bob"}, window: 20 * time.Millisecond, wantBatches: 1, wantSent: 2},
+		{name: "full batch without waiting", names: names(MaxBatchSize), window: time.Hour, wantBatches: 1, wantSent: MaxBatchSize},
+		{name: "full batch then window", names: names(MaxBatchSize + 3), window: 20 * time.Millisecond, wantBatches: 2, wantSent: MaxBatchSize + 3},
+		{name: "error fans out", names: []string{"ann", "bob"}, window: 20 * time.Millisecond, fetchErr: fetchErr, wantBatches: 1, wantSent: 2},
+		{name: "no data", names: []string{"ann", "bob"}, window: 20 * time.Millisecond, missing: map[string]bool{"bob": true}, wantBatches: 1, wantSent: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &recordingFetch{err: tt.fetchErr, missing: tt.missing}
+			b := newBatcher(f.fetch, tt.window, time.Second)
+
+			values, errs := lookupAll(context.Background(), b, tt.names)
+
+			for i, name := range tt.names {
+				switch {
+				case tt.fetchErr != nil:
+					if !errors.Is(errs[i], tt.fetchErr) {
+						t.Errorf("%s: error = %v, want %v", name, errs[i], tt.fetchErr)
+					}
+				case tt.missing[name]:
+					if !errors.Is(errs[i], ErrNoData) {
+						t.Errorf("%s: error = %v, want ErrNoData", name, errs[i])
+					}
+				case errs[i] != nil || values[i] != len(name):
+					t.Errorf("%s: got %d, %v; want %d", name, values[i], errs[i], len(name))
+				}
+			}
+
+			batches := f.sent()
+			sent := 0
+			for _, batch := range batches {
+				if len(batch) > MaxBatchSize {
+					t.Errorf("batch of %d names exceeds MaxBatchSize", len(batch))
+				}
+				sent += len(batch)
+			}
+			if len(batches) != tt.wantBatches || sent != tt.wantSent {
+				t.Errorf("sent %d names in %d batches, want %d in %d: %v", sent, len(batches), tt.wantSent, tt.wantBatches, batches)
+			}
+		})
+	}
+}
+
+func TestBatcherCallerCancelled(t *testing.T) {
+	f := &recordingFetch{}
+	b := newBatcher(f.fetch, 50*time.Millisecond, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.do(ctx, "ann"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+
+	// The batch is still sent for the other callers, and a later call is unaffected.
+	if v, err := b.do(context.Background(), "bob"); err != nil || v != 3 {
+		t.Fatalf("got %d, %v; want 3", v, err)
+	}
+}
+
+func names(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("name%02d", i)
+	}
+	return out
+}
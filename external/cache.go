@@ -0,0 +1,149 @@
+package external
+
+import (
+	"TestRest/pkg/logger"
+	"container/list"
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// CacheStore is a persistent cache of lookup results, keyed by normalized name and lookup kind
+// ("age", "gender" or "nationality"). Get returns nil when there is no fresh value.
+type CacheStore interface {
+	Get(ctx context.Context, name, kind string) ([]byte, error)
+	Set(ctx context.Context, name, kind string, value []byte, ttl time.Duration) error
+}
+
+// CachedEnricher answers lookups from an in-memory LRU, then from a CacheStore, and only calls the wrapped Enricher
+// when neither has a fresh result. Failed lookups are not cached. Where lookups were answered from is counted, by
+// kind, in the enrichment_cache_*_total metrics.
+type CachedEnricher struct {
+	next      Enricher
+	memory    *lru
+	store     CacheStore
+	memoryTTL time.Duration
+	storeTTL  time.Duration
+}
+
+var _ Enricher = (*CachedEnricher)(nil)
+
+// NewCachedEnricher wraps next with a cache sized and timed by cfg. store may be nil to cache in memory only.
+func NewCachedEnricher(next Enricher, store CacheStore, cfg Config) *CachedEnricher {
+	return &CachedEnricher{
+		next:      next,
+		memory:    newLRU(cfg.CacheSize),
+		store:     store,
+		memoryTTL: cfg.CacheMemoryTTL,
+		storeTTL:  cfg.CacheTTL,
+	}
+}
+
+func (c *CachedEnricher) Age(ctx context.Context, name string) (Age, error) {
+	return cached(ctx, c, "age", name, c.next.Age)
+}
+
+func (c *CachedEnricher) Gender(ctx context.Context, name string) (Gender, error) {
+	return cached(ctx, c, "gender", name, c.next.Gender)
+}
+
+func (c *CachedEnricher) Nationality(ctx context.Context, name string) (Nationality, error) {
+	return cached(ctx, c, "nationality", name, c.next.Nationality)
+}
+
+func cached[T any](ctx context.Context, c *CachedEnricher, kind, name string, fetch func(context.Context, string) (T, error)) (T, error) {
+	key := normalizeName(name)
+
+	if v, ok := c.memory.get(kind + ":" + key); ok {
+		cacheMemoryHits.WithLabelValues(kind).Inc()
+		return v.(T), nil
+	}
+
+	if c.store != nil {
+		data, err := c.store.Get(ctx, key, kind)
+		if err != nil {
+			logger.GetLoggerFromContext(ctx).Warn(ctx, "Enrichment cache read failed", zap.String("kind", kind), zap.Error(err))
+		}
+		var v T
+		if data != nil && json.Unmarshal(data, &v) == nil {
+			cacheStoreHits.WithLabelValues(kind).Inc()
+			c.memory.put(kind+":"+key, v, c.memoryTTL)
+			return v, nil
+		}
+	}
+
+	cacheMisses.WithLabelValues(kind).Inc()
+	v, err := fetch(ctx, name)
+	if err != nil {
+		return v, err
+	}
+
+	c.memory.put(kind+":"+key, v, c.memoryTTL)
+	if c.store != nil {
+		data, _ := json.Marshal(v)
+		if err := c.store.Set(ctx, key, kind, data, c.storeTTL); err != nil {
+			logger.GetLoggerFromContext(ctx).Warn(ctx, "Enrichment cache write failed", zap.String("kind", kind), zap.Error(err))
+		}
+	}
+	return v, nil
+}
+
+// lru is a fixed-size least-recently-used cache whose entries also expire after a TTL.
+type lru struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type lruEntry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+func newLRU(capacity int) *lru {
+	return &lru{capacity: capacity, items: map[string]*list.Element{}, order: list.New()}
+}
+
+func (c *lru) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *lru) put(key string, value interface{}, ttl time.Duration) {
+	if c.capacity <= 0 || ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value = &lruEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)}
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
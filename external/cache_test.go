@@ -0,0 +1,222 @@
+package external
+
+import (
+	"TestRest/pkg/logger/loggertest"
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	type op struct {
+		put    string // key to put with ttl; when empty, get is looked up instead
+		ttl    time.Duration
+		get    string
+		wantOK bool
+		sleep  time.Duration // before the operation
+	}
+	tests := []struct {
+		name     string
+		capacity int
+		ops      []op
+	}{
+		{
+			name:     "hit and miss",
+			capacity: 2,
+			ops: []op{
+				{put: "a", ttl: time.Hour},
+				{get: "a", wantOK: true},
+				{get: "b", wantOK: false},
+			},
+		},
+		{
+			name:     "evicts least recently used",
+			capacity: 2,
+			ops: []op{
+				{put: "a", ttl: time.Hour},
+				{put: "b", ttl: time.Hour},
+				{get: "a", wantOK: true},
+				{put: "c", ttl: time.Hour},
+				{get: "b", wantOK: false},
+				{get: "a", wantOK: true},
+				{get: "c", wantOK: true},
+			},
+		},
+		{
+			name:     "overwrite refreshes recency",
+			capacity: 2,
+			ops: []op{
+				{put: "a", ttl: time.Hour},
+				{put: "b", ttl: time.Hour},
+				{put: "a", ttl: time.Hour},
+				{put: "c", ttl: time.Hour},
+				{get: "a", wantOK: true},
+				{get: "b", wantOK: false},
+			},
+		},
+		{
+			name:     "expires after ttl",
+			capacity: 2,
+			ops: []op{
+				{put: "a", ttl: 10 * time.Millisecond},
+				{put: "b", ttl: time.Hour},
+				{get: "a", wantOK: false, sleep: 20 * time.Millisecond},
+				{get: "b", wantOK: true},
+			},
+		},
+		{
+			name:     "zero capacity caches nothing",
+			capacity: 0,
+			ops: []op{
+				{put: "a", ttl: time.Hour},
+				{get: "a", wantOK: false},
+			},
+		},
+		{
+			name:     "zero ttl caches nothing",
+			capacity: 2,
+			ops: []op{
+				{put: "a", ttl: 0},
+				{get: "a", wantOK: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newLRU(tt.capacity)
+			for i, o := range tt.ops {
+				time.Sleep(o.sleep)
+				if o.put != "" {
+					c.put(o.put, o.put, o.ttl)
+					continue
+				}
+				v, ok := c.get(o.get)
+				if ok != o.wantOK || (ok && v != o.get) {
+					t.Errorf("op %d: get(%q) = %v, %t; want ok %t", i, o.get, v, ok, o.wantOK)
+				}
+			}
+			if len(c.items) != c.order.Len() || (tt.capacity > 0 && c.order.Len() > tt.capacity) {
+				t.Errorf("%d items indexed, %d in order, capacity %d", len(c.items), c.order.Len(), tt.capacity)
+			}
+		})
+	}
+}
+
+// countingEnricher answers every lookup with the length of the name and counts the calls.
+type countingEnricher struct {
+	calls int
+}
+
+func (e *countingEnricher) Age(_ context.Context, name string) (Age, error) {
+	e.calls++
+	return Age{Age: len(name)}, nil
+}
+
+func (e *countingEnricher) Gender(context.Context, string) (Gender, error) {
+	e.calls++
+	return Gender{Gender: "f"}, nil
+}
+
+func (e *countingEnricher) Nationality(context.Context, string) (Nationality, error) {
+	e.calls++
+	return Nationality{CountryID: "RU"}, nil
+}
+
+// mapStore is an in-memory CacheStore.
+type mapStore map[string][]byte
+
+func (s mapStore) Get(_ context.Context, name, kind string) ([]byte, error) {
+	return s[kind+":"+name], nil
+}
+
+func (s mapStore) Set(_ context.Context, name, kind string, value []byte, _ time.Duration) error {
+	s[kind+":"+name] = value
+	return nil
+}
+
+// cacheCounts are the enrichment_cache_*_total metrics of one kind. The metrics are global, so tests compare the
+// counts from before and after their lookups.
+type cacheCounts struct {
+	memoryHits, storeHits, misses float64
+}
+
+func readCacheCounts(kind string) cacheCounts {
+	return cacheCounts{
+		memoryHits: testutil.ToFloat64(cacheMemoryHits.WithLabelValues(kind)),
+		storeHits:  testutil.ToFloat64(cacheStoreHits.WithLabelValues(kind)),
+		misses:     testutil.ToFloat64(cacheMisses.WithLabelValues(kind)),
+	}
+}
+
+func (c cacheCounts) since(before cacheCounts) cacheCounts {
+	return cacheCounts{c.memoryHits - before.memoryHits, c.storeHits - before.storeHits, c.misses - before.misses}
+}
+
+func TestCachedEnricher(t *testing.T) {
+	ctx := context.Background()
+	cfg := Config{CacheSize: 10, CacheMemoryTTL: time.Hour, CacheTTL: time.Hour}
+	store := mapStore{}
+
+	before := readCacheCounts("age")
+
+	next := &countingEnricher{}
+	c := NewCachedEnricher(next, store, cfg)
+	for _, name := range []string{"Ivan", " ivan ", "IVAN"} {
+		// Every spelling is answered with the result for the first one.
+		if age, err := c.Age(ctx, name); err != nil || age.Age != len("Ivan") {
+			t.Fatalf("Age(%q) = %v, %v", name, age, err)
+		}
+	}
+	if next.calls != 1 {
+		t.Errorf("provider called %d times for one normalized name, want 1", next.calls)
+	}
+	if got, want := readCacheCounts("age").since(before), (cacheCounts{memoryHits: 2, misses: 1}); got != want {
+		t.Errorf("counted %+v, want %+v", got, want)
+	}
+
+	// A new process starts with an empty memory but shares the store.
+	next = &countingEnricher{}
+	c = NewCachedEnricher(next, store, cfg)
+	before = readCacheCounts("age")
+	if _, err := c.Age(ctx, "Ivan"); err != nil {
+		t.Fatal(err)
+	}
+	if next.calls != 0 {
+		t.Errorf("provider called %d times despite a stored result", next.calls)
+	}
+	if got, want := readCacheCounts("age").since(before), (cacheCounts{storeHits: 1}); got != want {
+		t.Errorf("counted %+v, want %+v", got, want)
+	}
+}
+
+// failingStore is a CacheStore that is down.
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string, string) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (failingStore) Set(context.Context, string, string, []byte, time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func TestCachedEnricherStoreDown(t *testing.T) {
+	ctx, logs := loggertest.NewObserved(context.Background())
+	next := &countingEnricher{}
+	c := NewCachedEnricher(next, failingStore{}, Config{CacheSize: 10, CacheMemoryTTL: time.Hour, CacheTTL: time.Hour})
+
+	// A failing store only costs the provider call, and is logged.
+	if gender, err := c.Gender(ctx, "Ivan"); err != nil || gender.Gender != "f" {
+		t.Fatalf("Gender = %v, %v", gender, err)
+	}
+	if next.calls != 1 {
+		t.Errorf("provider called %d times, want 1", next.calls)
+	}
+	for _, msg := range []string{"Enrichment cache read failed", "Enrichment cache write failed"} {
+		if n := logs.FilterMessage(msg).Len(); n != 1 {
+			t.Errorf("%q logged %d times, want 1", msg, n)
+		}
+	}
+}
@@ -1,91 +1,280 @@
+// Package external enriches a person's name with age, gender and nationality estimates.
+// Estimates come from an Enricher: either the public agify/genderize/nationalize APIs or a local table.
 package external
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 )
 
-func GetAge(name string) (int, error) {
-	resp, err := http.Get(fmt.Sprintf("https://api.agify.io?name=%s", name))
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
+type Config struct {
+	// Provider selects the Enricher: "http" for the public APIs or "local" for the table at TablePath.
+	Provider string `yaml:"PROVIDER" env:"ENRICHMENT_PROVIDER" env-default:"http"`
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return 0, err
-	}
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, err
-	}
-	age, ok := result["age"].(float64)
-	if !ok {
-		return 0, errors.New("age is not a number")
-	}
-	return int(age), nil
+	AgeURL         string `yaml:"AGE_API_URL" env:"AGE_API_URL" env-default:"https://api.agify.io"`
+	GenderURL      string `yaml:"GENDER_API_URL" env:"GENDER_API_URL" env-default:"https://api.genderize.io"`
+	NationalityURL string `yaml:"NATIONALITY_API_URL" env:"NATIONALITY_API_URL" env-default:"https://api.nationalize.io"`
+
+	TablePath string `yaml:"TABLE_PATH" env:"ENRICHMENT_TABLE_PATH" env-default:"./external/data/names.csv"`
+
+	// Timeout bounds each of the three lookups.
+	Timeout time.Duration `yaml:"TIMEOUT" env:"ENRICHMENT_TIMEOUT" env-default:"3s"`
+	// FailurePolicy decides what happens when a lookup fails: "fail", "null" or "defaults".
+	FailurePolicy FailurePolicy `yaml:"FAILURE_POLICY" env:"ENRICHMENT_FAILURE_POLICY" env-default:"fail"`
+
+	// Defaults used by the "defaults" policy. An empty gender or nationality is stored as null.
+	DefaultAge         int    `yaml:"DEFAULT_AGE" env:"ENRICHMENT_DEFAULT_AGE" env-default:"0"`
+	DefaultGender      string `yaml:"DEFAULT_GENDER" env:"ENRICHMENT_DEFAULT_GENDER"`
+	DefaultNationality string `yaml:"DEFAULT_NATIONALITY" env:"ENRICHMENT_DEFAULT_NATIONALITY"`
+
+	// BatchEnabled coalesces lookups made within BatchWindow into batch requests to the provider.
+	BatchEnabled bool          `yaml:"BATCH_ENABLED" env:"ENRICHMENT_BATCH_ENABLED" env-default:"true"`
+	BatchWindow  time.Duration `yaml:"BATCH_WINDOW" env:"ENRICHMENT_BATCH_WINDOW" env-default:"10ms"`
+
+	// CacheEnabled puts a CachedEnricher in front of the provider.
+	CacheEnabled bool `yaml:"CACHE_ENABLED" env:"ENRICHMENT_CACHE_ENABLED" env-default:"true"`
+	// CacheSize is the number of entries kept in memory; each name takes up to three.
+	CacheSize      int           `yaml:"CACHE_SIZE" env:"ENRICHMENT_CACHE_SIZE" env-default:"10000"`
+	CacheMemoryTTL time.Duration `yaml:"CACHE_MEMORY_TTL" env:"ENRICHMENT_CACHE_MEMORY_TTL" env-default:"1h"`
+	// CacheTTL is how long results stay in the persistent store.
+	CacheTTL time.Duration `yaml:"CACHE_TTL" env:"ENRICHMENT_CACHE_TTL" env-default:"720h"`
+}
+
+// FailurePolicy decides how Client.Enrich treats a failed lookup.
+type FailurePolicy string
+
+const (
+	// PolicyFail fails the whole enrichment.
+	PolicyFail FailurePolicy = "fail"
+	// PolicyNull leaves the failed field empty.
+	PolicyNull FailurePolicy = "null"
+	// PolicyDefaults fills the failed field from Config defaults.
+	PolicyDefaults FailurePolicy = "defaults"
+)
+
+type Age struct {
+	Age int `json:"age"`
+	// Count is the number of samples the estimate is based on.
+	Count int `json:"count"`
+}
+
+type Gender struct {
+	// Gender is "m" or "f".
+	Gender      string  `json:"gender"`
+	Probability float64 `json:"probability"`
+}
+
+type Nationality struct {
+	// CountryID is an ISO 3166-1 alpha-2 code.
+	CountryID   string  `json:"country_id"`
+	Probability float64 `json:"probability"`
+}
+
+// Enricher estimates demographic data for a first name.
+type Enricher interface {
+	Age(ctx context.Context, name string) (Age, error)
+	Gender(ctx context.Context, name string) (Gender, error)
+	Nationality(ctx context.Context, name string) (Nationality, error)
+}
+
+// ErrNoData is returned when a provider has no estimate for a name.
+var ErrNoData = errors.New("no data for name")
+
+// ProviderError reports which of the three lookups failed.
+type ProviderError struct {
+	// Provider is "age", "gender" or "nationality".
+	Provider string
+	Err      error
 }
 
-func GetGender(name string) (string, error) {
-	resp, err := http.Get(fmt.Sprintf("https://api.genderize.io/?name=%s", name))
-	if err != nil {
-		return "", err
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("unable to fetch %s: %v", e.Provider, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Result holds the estimates for one name. A nil field means the lookup failed and the failure policy left it empty.
+type Result struct {
+	Age         *Age
+	Gender      *Gender
+	Nationality *Nationality
+}
+
+// Client runs the three lookups of an Enricher concurrently, each under its own deadline, and applies the failure
+// policy to the ones that fail.
+type Client struct {
+	enricher Enricher
+	timeout  time.Duration
+	policy   FailurePolicy
+	defaults Result
+}
+
+func NewClient(enricher Enricher, cfg Config) (*Client, error) {
+	c := &Client{enricher: enricher, timeout: cfg.Timeout, policy: cfg.FailurePolicy}
+
+	switch cfg.FailurePolicy {
+	case "":
+		c.policy = PolicyFail
+	case PolicyFail, PolicyNull:
+	case PolicyDefaults:
+		c.defaults.Age = &Age{Age: cfg.DefaultAge}
+		if cfg.DefaultGender != "" {
+			gender, err := normalizeGender(cfg.DefaultGender)
+			if err != nil {
+				return nil, fmt.Errorf("invalid default gender: %w", err)
+			}
+			c.defaults.Gender = &Gender{Gender: gender}
+		}
+		if cfg.DefaultNationality != "" {
+			c.defaults.Nationality = &Nationality{CountryID: strings.ToUpper(cfg.DefaultNationality)}
+		}
+	default:
+		return nil, fmt.Errorf("unknown enrichment failure policy %q", cfg.FailurePolicy)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	return c, nil
+}
+
+// Fields selects which lookups to run.
+type Fields uint8
+
+const (
+	FieldAge Fields = 1 << iota
+	FieldGender
+	FieldNationality
+
+	AllFields = FieldAge | FieldGender | FieldNationality
+)
+
+// Enrich looks up age, gender and nationality for name. With PolicyFail the returned error is a *ProviderError for
+// the first failed lookup; with the other policies failed lookups are replaced and no error is returned.
+func (c *Client) Enrich(ctx context.Context, name string) (*Result, error) {
+	return c.EnrichFields(ctx, name, AllFields)
+}
+
+// EnrichFields is Enrich restricted to the given fields; the other fields of the result are nil.
+func (c *Client) EnrichFields(ctx context.Context, name string, fields Fields) (*Result, error) {
+	var res Result
+	var ageErr, genderErr, nationalityErr error
+
+	var wg sync.WaitGroup
+	if fields&FieldAge != 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := c.lookupContext(ctx)
+			defer cancel()
+			age, err := c.enricher.Age(ctx, name)
+			res.Age, ageErr = &age, err
+		}()
 	}
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", err
+	if fields&FieldGender != 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := c.lookupContext(ctx)
+			defer cancel()
+			gender, err := c.enricher.Gender(ctx, name)
+			res.Gender, genderErr = &gender, err
+		}()
 	}
-	gender, ok := result["gender"].(string)
-	if !ok {
-		return "", errors.New("failed to fetch gender")
+	if fields&FieldNationality != 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := c.lookupContext(ctx)
+			defer cancel()
+			nationality, err := c.enricher.Nationality(ctx, name)
+			res.Nationality, nationalityErr = &nationality, err
+		}()
 	}
-	switch strings.ToLower(gender) {
-	case "male":
-		return "m", nil
-	case "female":
-		return "f", nil
-	default:
-		return "", errors.New("unknown gender")
+	wg.Wait()
+
+	if ageErr != nil {
+		if c.policy == PolicyFail {
+			return nil, &ProviderError{Provider: "age", Err: ageErr}
+		}
+		res.Age = c.defaults.Age
+	}
+	if genderErr != nil {
+		if c.policy == PolicyFail {
+			return nil, &ProviderError{Provider: "gender", Err: genderErr}
+		}
+		res.Gender = c.defaults.Gender
 	}
+	if nationalityErr != nil {
+		if c.policy == PolicyFail {
+			return nil, &ProviderError{Provider: "nationality", Err: nationalityErr}
+		}
+		res.Nationality = c.defaults.Nationality
+	}
+
+	return &res, nil
 }
 
-type NationalityResponse struct {
-	Country []struct {
-		CountryID   string  `json:"country_id"`
-		Probability float64 `json:"probability"`
-	} `json:"country"`
+// Lookup is one name for EnrichMany together with the fields it still needs.
+type Lookup struct {
+	Name   string
+	Fields Fields
 }
 
-func GetNationality(name string) (string, error) {
-	resp, err := http.Get(fmt.Sprintf("https://api.nationalize.io/?name=%s", name))
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+// maxConcurrentEnrichments bounds the goroutines EnrichMany starts at once.
+const maxConcurrentEnrichments = 5 * MaxBatchSize
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	var result NationalityResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", err
+// EnrichMany runs the lookups concurrently, so that a BatchingEnricher can group them into batch requests. Results
+// and errors are in the order of lookups.
+func (c *Client) EnrichMany(ctx context.Context, lookups []Lookup) ([]*Result, []error) {
+	results := make([]*Result, len(lookups))
+	errs := make([]error, len(lookups))
+
+	sem := make(chan struct{}, maxConcurrentEnrichments)
+	var wg sync.WaitGroup
+	for i, lookup := range lookups {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = c.EnrichFields(ctx, lookup.Name, lookup.Fields)
+		}()
 	}
+	wg.Wait()
+
+	return results, errs
+}
 
-	if len(result.Country) == 0 {
-		return "", errors.New("no countries found in response")
+func (c *Client) lookupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// normalizeName is the key names are looked up and cached under.
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
 
-	return result.Country[0].CountryID, nil
+// Pinger is implemented by enrichers backed by remote services, to check that those services can be reached.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// New builds the Enricher selected by cfg.Provider.
+func New(cfg Config, client *http.Client) (Enricher, error) {
+	switch cfg.Provider {
+	case "", "http":
+		return NewHTTPEnricher(cfg, client), nil
+	case "local":
+		return NewLocalEnricher(cfg.TablePath)
+	default:
+		return nil, fmt.Errorf("unknown enrichment provider %q", cfg.Provider)
+	}
 }
@@ -0,0 +1,245 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const tracerName = "TestRest/external"
+
+// HTTPEnricher queries agify, genderize and nationalize (or compatible services) at the configured base URLs.
+type HTTPEnricher struct {
+	client         *http.Client
+	ageURL         string
+	genderURL      string
+	nationalityURL string
+}
+
+var (
+	_ Enricher      = (*HTTPEnricher)(nil)
+	_ BatchEnricher = (*HTTPEnricher)(nil)
+	_ Pinger        = (*HTTPEnricher)(nil)
+)
+
+func NewHTTPEnricher(cfg Config, client *http.Client) *HTTPEnricher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPEnricher{
+		client:         client,
+		ageURL:         cfg.AgeURL,
+		genderURL:      cfg.GenderURL,
+		nationalityURL: cfg.NationalityURL,
+	}
+}
+
+type ageResponse struct {
+	Count int  `json:"count"`
+	Age   *int `json:"age"`
+}
+
+func (r ageResponse) result() (*Age, error) {
+	if r.Age == nil {
+		return nil, nil
+	}
+	return &Age{Age: *r.Age, Count: r.Count}, nil
+}
+
+type genderResponse struct {
+	Gender      *string `json:"gender"`
+	Probability float64 `json:"probability"`
+}
+
+func (r genderResponse) result() (*Gender, error) {
+	if r.Gender == nil {
+		return nil, nil
+	}
+	gender, err := normalizeGender(*r.Gender)
+	if err != nil {
+		return nil, err
+	}
+	return &Gender{Gender: gender, Probability: r.Probability}, nil
+}
+
+type nationalityResponse struct {
+	Country []Nationality `json:"country"`
+}
+
+func (r nationalityResponse) result() (*Nationality, error) {
+	if len(r.Country) == 0 {
+		return nil, nil
+	}
+	return &r.Country[0], nil
+}
+
+func (e *HTTPEnricher) Age(ctx context.Context, name string) (Age, error) {
+	return single[ageResponse, Age](ctx, e, "age", e.ageURL, name)
+}
+
+func (e *HTTPEnricher) Gender(ctx context.Context, name string) (Gender, error) {
+	return single[genderResponse, Gender](ctx, e, "gender", e.genderURL, name)
+}
+
+func (e *HTTPEnricher) Nationality(ctx context.Context, name string) (Nationality, error) {
+	return single[nationalityResponse, Nationality](ctx, e, "nationality", e.nationalityURL, name)
+}
+
+func (e *HTTPEnricher) AgeBatch(ctx context.Context, names []string) ([]*Age, error) {
+	return batch[ageResponse, Age](ctx, e, "age", e.ageURL, names)
+}
+
+func (e *HTTPEnricher) GenderBatch(ctx context.Context, names []string) ([]*Gender, error) {
+	return batch[genderResponse, Gender](ctx, e, "gender", e.genderURL, names)
+}
+
+func (e *HTTPEnricher) NationalityBatch(ctx context.Context, names []string) ([]*Nationality, error) {
+	return batch[nationalityResponse, Nationality](ctx, e, "nationality", e.nationalityURL, names)
+}
+
+// response is a provider's JSON answer for one name.
+type response[T any] interface {
+	result() (*T, error)
+}
+
+// single looks up one name with ?name=<name>.
+func single[R response[T], T any](ctx context.Context, e *HTTPEnricher, provider, baseURL, name string) (T, error) {
+	var zero T
+	var resp R
+	if err := e.get(ctx, provider, baseURL, url.Values{"name": {name}}, &resp); err != nil {
+		return zero, err
+	}
+	result, err := resp.result()
+	if err != nil {
+		return zero, err
+	}
+	if result == nil {
+		return zero, ErrNoData
+	}
+	return *result, nil
+}
+
+// batch looks up names with ?name[]=a&name[]=b, at most MaxBatchSize per request. A name whose answer cannot be
+// interpreted gets a nil result rather than failing the whole batch.
+func batch[R response[T], T any](ctx context.Context, e *HTTPEnricher, provider, baseURL string, names []string) ([]*T, error) {
+	results := make([]*T, 0, len(names))
+	for start := 0; start < len(names); start += MaxBatchSize {
+		chunk := names[start:min(start+MaxBatchSize, len(names))]
+
+		var resp []R
+		if err := e.get(ctx, provider, baseURL, url.Values{"name[]": chunk}, &resp); err != nil {
+			return nil, err
+		}
+		if len(resp) != len(chunk) {
+			return nil, fmt.Errorf("provider returned %d results for %d names", len(resp), len(chunk))
+		}
+		for _, r := range resp {
+			result, _ := r.result()
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// get calls baseURL with the given query and decodes the JSON response into dst. The call is recorded in the
+// metrics of provider and as a client span whose context is sent to the provider in the traceparent header.
+func (e *HTTPEnricher) get(ctx context.Context, provider, baseURL string, query url.Values, dst interface{}) (err error) {
+	start := time.Now()
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "enrichment "+provider,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("enrichment.provider", provider),
+			attribute.Int("enrichment.names", len(query["name"])+len(query["name[]"])),
+		),
+	)
+	defer func() {
+		observe(provider, start, err)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid provider url %q: %w", baseURL, err)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	q := u.Query()
+	for k, v := range query {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	span.SetAttributes(attribute.String("server.address", u.Host))
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		// The *url.Error of a failed request quotes the URL, whose query holds the names looked up. Only the host is
+		// kept, so that the error can be recorded in the span and logged.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("%s %s: %w", urlErr.Op, u.Host, urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with %s", u.Host, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", u.Host, err)
+	}
+	return nil
+}
+
+func normalizeGender(gender string) (string, error) {
+	switch strings.ToLower(gender) {
+	case "male", "m":
+		return "m", nil
+	case "female", "f":
+		return "f", nil
+	default:
+		return "", fmt.Errorf("unknown gender %q", gender)
+	}
+}
+
+// Ping checks that every provider answers HTTP. Any status below 500 counts, since the providers reject requests
+// without a name.
+func (e *HTTPEnricher) Ping(ctx context.Context) error {
+	for _, baseURL := range []string{e.ageURL, e.genderURL, e.nationalityURL} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, baseURL, nil)
+		if err != nil {
+			return fmt.Errorf("invalid provider url %q: %w", baseURL, err)
+		}
+		resp, err := e.client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%s responded with %s", req.URL.Host, resp.Status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package external
+
+import (
+	"TestRest/internal/tracing/tracingtest"
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPEnricherTracing(t *testing.T) {
+	exporter, _ := tracingtest.NewProvider()
+
+	tests := []struct {
+		name     string
+		status   int
+		down     bool
+		wantCode codes.Code
+	}{
+		{name: "ok", status: http.StatusOK, wantCode: codes.Unset},
+		{name: "provider error", status: http.StatusBadGateway, wantCode: codes.Error},
+		{name: "provider unreachable", down: true, wantCode: codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+			var received trace.SpanContext
+			provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+				received = trace.SpanContextFromContext(ctx)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{"count":10,"age":30}`))
+			}))
+			defer provider.Close()
+			if tt.down {
+				provider.Close()
+			}
+
+			ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+			_, _ = NewHTTPEnricher(Config{AgeURL: provider.URL}, provider.Client()).Age(ctx, "Ivan")
+			parent.End()
+
+			spans := exporter.GetSpans().Snapshots()
+			if len(spans) != 2 {
+				t.Fatalf("recorded %d spans, want the enrichment call and its parent", len(spans))
+			}
+			call := spans[0]
+			if call.Name() != "enrichment age" || call.SpanKind() != trace.SpanKindClient {
+				t.Errorf("span %q of kind %s, want enrichment age of kind client", call.Name(), call.SpanKind())
+			}
+			if call.Parent().SpanID() != parent.SpanContext().SpanID() {
+				t.Errorf("enrichment span is not a child of the request span")
+			}
+			if call.Status().Code != tt.wantCode {
+				t.Errorf("span status = %s, want %s", call.Status().Code, tt.wantCode)
+			}
+			// Errors are recorded without the URL, whose query holds the name.
+			if strings.Contains(call.Status().Description, "Ivan") {
+				t.Errorf("span status %q contains the name", call.Status().Description)
+			}
+			for _, event := range call.Events() {
+				for _, kv := range event.Attributes {
+					if strings.Contains(kv.Value.Emit(), "Ivan") {
+						t.Errorf("span event %s has %s = %q", event.Name, kv.Key, kv.Value.Emit())
+					}
+				}
+			}
+			if tt.down {
+				return
+			}
+
+			// The provider sees the enrichment span as the caller.
+			if received.TraceID() != call.SpanContext().TraceID() || received.SpanID() != call.SpanContext().SpanID() {
+				t.Errorf("provider received trace %s/%s, want %s/%s", received.TraceID(), received.SpanID(),
+					call.SpanContext().TraceID(), call.SpanContext().SpanID())
+			}
+		})
+	}
+}
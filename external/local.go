@@ -0,0 +1,151 @@
+package external
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LocalEnricher answers lookups from a static CSV table, so it works without network access.
+//
+// The table has the header
+//
+//	name,age,gender,gender_probability,nationality,nationality_probability
+//
+// Names are matched case-insensitively. A row named "*" is used for names that are not in the table; without it
+// such names yield ErrNoData.
+type LocalEnricher struct {
+	entries map[string]localEntry
+}
+
+type localEntry struct {
+	age         Age
+	gender      Gender
+	nationality Nationality
+}
+
+var (
+	_ Enricher      = (*LocalEnricher)(nil)
+	_ BatchEnricher = (*LocalEnricher)(nil)
+)
+
+const localDefaultName = "*"
+
+func NewLocalEnricher(path string) (*LocalEnricher, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open enrichment table: %w", err)
+	}
+	defer f.Close()
+
+	e, err := ReadLocalTable(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read enrichment table %s: %w", path, err)
+	}
+	return e, nil
+}
+
+// ReadLocalTable builds a LocalEnricher from CSV data in the format described on LocalEnricher.
+func ReadLocalTable(r io.Reader) (*LocalEnricher, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 6
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &LocalEnricher{entries: map[string]localEntry{}}
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(record[0], "name") {
+			continue
+		}
+		res, err := parseLocalRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		e.entries[normalizeName(record[0])] = res
+	}
+	return e, nil
+}
+
+func parseLocalRecord(record []string) (localEntry, error) {
+	var res localEntry
+	var err error
+
+	if res.age.Age, err = strconv.Atoi(record[1]); err != nil {
+		return res, fmt.Errorf("invalid age %q", record[1])
+	}
+	res.age.Count = 1
+	if res.gender.Gender, err = normalizeGender(record[2]); err != nil {
+		return res, err
+	}
+	if res.gender.Probability, err = strconv.ParseFloat(record[3], 64); err != nil {
+		return res, fmt.Errorf("invalid gender probability %q", record[3])
+	}
+	res.nationality.CountryID = strings.ToUpper(strings.TrimSpace(record[4]))
+	if res.nationality.Probability, err = strconv.ParseFloat(record[5], 64); err != nil {
+		return res, fmt.Errorf("invalid nationality probability %q", record[5])
+	}
+	return res, nil
+}
+
+func (e *LocalEnricher) lookup(name string) (localEntry, error) {
+	if res, ok := e.entries[normalizeName(name)]; ok {
+		return res, nil
+	}
+	if res, ok := e.entries[localDefaultName]; ok {
+		return res, nil
+	}
+	return localEntry{}, ErrNoData
+}
+
+func (e *LocalEnricher) Age(_ context.Context, name string) (Age, error) {
+	res, err := e.lookup(name)
+	return res.age, err
+}
+
+func (e *LocalEnricher) Gender(_ context.Context, name string) (Gender, error) {
+	res, err := e.lookup(name)
+	return res.gender, err
+}
+
+func (e *LocalEnricher) Nationality(_ context.Context, name string) (Nationality, error) {
+	res, err := e.lookup(name)
+	return res.nationality, err
+}
+
+func (e *LocalEnricher) AgeBatch(_ context.Context, names []string) ([]*Age, error) {
+	results := make([]*Age, len(names))
+	for i, name := range names {
+		if res, err := e.lookup(name); err == nil {
+			results[i] = &res.age
+		}
+	}
+	return results, nil
+}
+
+func (e *LocalEnricher) GenderBatch(_ context.Context, names []string) ([]*Gender, error) {
+	results := make([]*Gender, len(names))
+	for i, name := range names {
+		if res, err := e.lookup(name); err == nil {
+			results[i] = &res.gender
+		}
+	}
+	return results, nil
+}
+
+func (e *LocalEnricher) NationalityBatch(_ context.Context, names []string) ([]*Nationality, error) {
+	results := make([]*Nationality, len(names))
+	for i, name := range names {
+		if res, err := e.lookup(name); err == nil {
+			results[i] = &res.nationality
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,43 @@
+package external
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"time"
+)
+
+var (
+	providerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "enrichment_provider_request_duration_seconds",
+		Help:    "Duration of requests to enrichment providers by provider (age, gender, nationality).",
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"provider"})
+
+	providerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "enrichment_provider_errors_total",
+		Help: "Failed requests to enrichment providers by provider (age, gender, nationality).",
+	}, []string{"provider"})
+
+	cacheMemoryHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "enrichment_cache_memory_hits_total",
+		Help: "Enrichment lookups answered from the in-memory cache by kind (age, gender, nationality).",
+	}, []string{"kind"})
+
+	cacheStoreHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "enrichment_cache_store_hits_total",
+		Help: "Enrichment lookups answered from the persistent cache by kind (age, gender, nationality).",
+	}, []string{"kind"})
+
+	cacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "enrichment_cache_misses_total",
+		Help: "Enrichment lookups passed on to the provider by the cache by kind (age, gender, nationality).",
+	}, []string{"kind"})
+)
+
+// observe records one provider request that started at start and ended with err.
+func observe(provider string, start time.Time, err error) {
+	providerDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+	if err != nil {
+		providerErrors.WithLabelValues(provider).Inc()
+	}
+}
@@ -1,8 +1,18 @@
 package config
 
 import (
+	"TestRest/external"
+	"TestRest/internal/health"
+	"TestRest/internal/tracing"
+	"TestRest/pkg/logger"
 	"TestRest/pkg/postgres"
+	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"reflect"
+	"time"
 )
 
 type Config struct {
@@ -11,17 +21,83 @@ type Config struct {
 	RESTHost string `yaml:"REST_HOST" env:"REST_HOST" env-default:"localhost"`
 	RESTPort int    `yaml:"REST_PORT" env:"REST_PORT" env-default:"8080"`
 
-	ExternalAPIs struct {
-		AgeURL         string `yaml:"AGE_API_URL" env:"AGE_API_URL" env-default:"https://api.agify.io"`
-		GenderURL      string `yaml:"GENDER_API_URL" env:"GENDER_API_URL" env-default:"https://api.genderize.io"`
-		NationalityURL string `yaml:"NATIONALITY_API_URL" env:"NATIONALITY_API_URL" env-default:"https://api.nationalize.io"`
-	} `yaml:"EXTERNAL_APIS"`
+	// Server timeouts. Streaming exports replace WriteTimeout with a deadline per flushed chunk, and imports replace
+	// ReadTimeout and WriteTimeout with deadlines per chunk of rows; ShutdownTimeout is how long in-flight requests may
+	// take to finish after SIGINT or SIGTERM.
+	ReadTimeout       time.Duration `yaml:"REST_READ_TIMEOUT" env:"REST_READ_TIMEOUT" env-default:"10s"`
+	ReadHeaderTimeout time.Duration `yaml:"REST_READ_HEADER_TIMEOUT" env:"REST_READ_HEADER_TIMEOUT" env-default:"5s"`
+	WriteTimeout      time.Duration `yaml:"REST_WRITE_TIMEOUT" env:"REST_WRITE_TIMEOUT" env-default:"60s"`
+	IdleTimeout       time.Duration `yaml:"REST_IDLE_TIMEOUT" env:"REST_IDLE_TIMEOUT" env-default:"120s"`
+	ShutdownTimeout   time.Duration `yaml:"REST_SHUTDOWN_TIMEOUT" env:"REST_SHUTDOWN_TIMEOUT" env-default:"30s"`
+
+	// LegacyRoutes keeps the deprecated /get, /post, /put and /delete routes registered alongside /people.
+	LegacyRoutes bool `yaml:"LEGACY_ROUTES" env:"LEGACY_ROUTES" env-default:"true"`
+
+	// RequireIfMatch rejects updates and deletes without an If-Match header with 428 Precondition Required. When false,
+	// If-Match is still honoured if sent. The legacy routes never require it.
+	RequireIfMatch bool `yaml:"REQUIRE_IF_MATCH" env:"REQUIRE_IF_MATCH" env-default:"true"`
+
+	// TrashRetention is how long deleted people can be restored before they are purged; 0 keeps them forever.
+	TrashRetention     time.Duration `yaml:"TRASH_RETENTION" env:"TRASH_RETENTION" env-default:"720h"`
+	TrashPurgeInterval time.Duration `yaml:"TRASH_PURGE_INTERVAL" env:"TRASH_PURGE_INTERVAL" env-default:"1h"`
+
+	ExternalAPIs external.Config `yaml:"EXTERNAL_APIS"`
+	Health       health.Config   `yaml:"HEALTH"`
+	Tracing      tracing.Config  `yaml:"TRACING"`
+	Logger       logger.Config   `yaml:"LOGGER"`
 }
 
-func New() (*Config, error) {
+// New reads the config from the environment or, if path is not empty, from the YAML file at path with the
+// environment variables that are set taking precedence over the file. Settings missing from both get their
+// env-default, and so do secrets the file only holds masked, as written by Print. The result is validated.
+func New(path string) (*Config, error) {
 	var cfg Config
 	if err := cleanenv.ReadEnv(&cfg); err != nil {
 		return nil, err
 	}
+
+	if path != "" {
+		// cleanenv.ReadConfig would apply the defaults again to any setting the file sets to its zero value, e.g.
+		// LEGACY_ROUTES: false, so the file is decoded over the defaults and the environment is applied again.
+		env := cfg
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if err := cleanenv.ParseYAML(f, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		envFields := fields(reflect.ValueOf(&env).Elem())
+		for i, f := range fields(reflect.ValueOf(&cfg).Elem()) {
+			_, ok := os.LookupEnv(f.Env)
+			masked := f.Secret() && f.Value.Kind() == reflect.String && f.Value.String() == secretMask
+			if ok && f.Env != "" || masked {
+				f.Value.Set(envFields[i].Value)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
+
+// Print writes cfg as YAML that New can read back. Secrets are masked, so New takes them from the environment.
+func (c Config) Print(w io.Writer) error {
+	masked := c
+	for _, f := range fields(reflect.ValueOf(&masked).Elem()) {
+		if f.Secret() && f.Value.Kind() == reflect.String && f.Value.String() != "" {
+			f.Value.SetString(secretMask)
+		}
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(masked); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrintReadBack(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "s3cret")
+	t.Setenv("LOG_PII_HASH_KEY", "k3y")
+	t.Setenv("REST_PORT", "9090")
+
+	cfg, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var printed bytes.Buffer
+	if err := cfg.Print(&printed); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(printed.String(), "s3cret") || strings.Contains(printed.String(), "k3y") {
+		t.Fatalf("secrets printed:\n%s", printed.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, printed.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	read, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Postgres.Password != "s3cret" || read.Logger.Redaction.PIIHashKey != "k3y" {
+		t.Errorf("password %q, hash key %q; want them from the environment", read.Postgres.Password, read.Logger.Redaction.PIIHashKey)
+	}
+	var reprinted bytes.Buffer
+	if err := read.Print(&reprinted); err != nil {
+		t.Fatal(err)
+	}
+	if reprinted.String() != printed.String() {
+		t.Errorf("read back as\n%s\nwant\n%s", reprinted.String(), printed.String())
+	}
+
+	// Without the environment, masked secrets fall back to their defaults instead of the mask.
+	os.Unsetenv("POSTGRES_PASSWORD")
+	os.Unsetenv("LOG_PII_HASH_KEY")
+	read, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Postgres.Password != "qwerty" || read.Logger.Redaction.PIIHashKey != "" {
+		t.Errorf("password %q, hash key %q; want the defaults", read.Postgres.Password, read.Logger.Redaction.PIIHashKey)
+	}
+	if read.RESTPort != 9090 {
+		t.Errorf("port = %d, want 9090 from the file", read.RESTPort)
+	}
+}
+
+func TestNewFileAndEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := "REST_PORT: 9090\nLEGACY_ROUTES: false\nPOSTGRES:\n  database: people\n  max_conns: 20\n"
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("POSTGRES_MAX_CONNS", "30")
+
+	cfg, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RESTPort != 9090 || cfg.LegacyRoutes || cfg.Postgres.Database != "people" {
+		t.Errorf("file settings not applied: port %d, legacy routes %t, database %q", cfg.RESTPort, cfg.LegacyRoutes, cfg.Postgres.Database)
+	}
+	if cfg.Postgres.MaxConns != 30 {
+		t.Errorf("max conns = %d, want 30 from the environment", cfg.Postgres.MaxConns)
+	}
+	if cfg.RESTHost != "localhost" {
+		t.Errorf("host = %q, want the default", cfg.RESTHost)
+	}
+}
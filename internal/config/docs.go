@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//go:generate go run ./gen ../../docs/config.md
+
+// WriteDocs writes a Markdown table of every setting with its YAML key, environment variable, type and default,
+// generated from the struct tags of Config.
+func WriteDocs(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Configuration\n\n")
+	b.WriteString("Generated from the struct tags of `internal/config` by `go generate ./internal/config`; do not edit.\n\n")
+	b.WriteString("Settings are read from the environment, or from the YAML file given with `--config` with the environment\n")
+	b.WriteString("variables that are set taking precedence. Run the service with `--print-config` to see the effective values.\n\n")
+	b.WriteString("| YAML key | Environment variable | Type | Default |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, f := range fields(reflect.ValueOf(&Config{}).Elem()) {
+		env := f.Env
+		if env != "" {
+			env = "`" + env + "`"
+		}
+		def := f.Default()
+		if def != "" {
+			def = "`" + def + "`"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", f.Path, env, typeName(f.Value.Type()), def)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func typeName(t reflect.Type) string {
+	switch {
+	case t == reflect.TypeOf(time.Duration(0)):
+		return "duration"
+	case t.Kind() == reflect.Slice:
+		return "list of " + typeName(t.Elem()) + " (comma-separated)"
+	default:
+		return t.Kind().String()
+	}
+}
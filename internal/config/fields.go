@@ -0,0 +1,58 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// secretMask replaces the value of fields tagged secret:"true" wherever the config is shown.
+const secretMask = "********"
+
+// field is one setting of Config, a leaf of its tree of structs.
+type field struct {
+	// Path is the YAML key of the setting, with the keys of its enclosing sections separated by dots.
+	Path  string
+	Env   string
+	Tag   reflect.StructTag
+	Value reflect.Value
+}
+
+// Default returns the env-default tag of the field, masked for secrets.
+func (f field) Default() string {
+	def := f.Tag.Get("env-default")
+	if def != "" && f.Secret() {
+		return secretMask
+	}
+	return def
+}
+
+func (f field) Secret() bool {
+	return f.Tag.Get("secret") == "true"
+}
+
+// fields lists the settings of the struct v in declaration order.
+func fields(v reflect.Value) []field {
+	return appendFields(nil, v, "")
+}
+
+func appendFields(out []field, v reflect.Value, prefix string) []field {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if name == "" {
+			name = sf.Name
+		}
+		if sf.Type.Kind() == reflect.Struct && sf.Type != reflect.TypeOf(time.Time{}) {
+			out = appendFields(out, v.Field(i), prefix+name+".")
+			continue
+		}
+		env, _, _ := strings.Cut(sf.Tag.Get("env"), ",")
+		out = append(out, field{Path: prefix + name, Env: env, Tag: sf.Tag, Value: v.Field(i)})
+	}
+	return out
+}
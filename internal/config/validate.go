@@ -0,0 +1,94 @@
+package config
+
+import (
+	"TestRest/external"
+	"TestRest/pkg/logger"
+	"fmt"
+	"go.uber.org/zap/zapcore"
+	"net/url"
+	"strings"
+)
+
+// Errors lists every invalid setting of a Config.
+type Errors []string
+
+func (e Errors) Error() string {
+	return "invalid config:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// Validate checks the settings that would otherwise fail later, or silently misbehave, and reports all of them at
+// once. Settings are named by their environment variable.
+func (c *Config) Validate() error {
+	var errs Errors
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.RESTPort > 0 && c.RESTPort <= 65535, "REST_PORT must be between 1 and 65535, got %d", c.RESTPort)
+	check(c.ReadTimeout >= 0, "REST_READ_TIMEOUT must not be negative")
+	check(c.ReadHeaderTimeout >= 0, "REST_READ_HEADER_TIMEOUT must not be negative")
+	check(c.WriteTimeout >= 0, "REST_WRITE_TIMEOUT must not be negative")
+	check(c.IdleTimeout >= 0, "REST_IDLE_TIMEOUT must not be negative")
+	check(c.ShutdownTimeout > 0, "REST_SHUTDOWN_TIMEOUT must be positive, got %s", c.ShutdownTimeout)
+
+	check(c.Postgres.Host != "", "POSTGRES_HOST must not be empty")
+	check(c.Postgres.Port > 0, "POSTGRES_PORT must be between 1 and 65535, got %d", c.Postgres.Port)
+	check(c.Postgres.Database != "", "POSTGRES_DATABASE must not be empty")
+	check(c.Postgres.MinConns >= 0, "POSTGRES_MIN_CONNS must not be negative, got %d", c.Postgres.MinConns)
+	check(c.Postgres.MaxConns > 0, "POSTGRES_MAX_CONNS must be positive, got %d", c.Postgres.MaxConns)
+	check(c.Postgres.MaxConns >= c.Postgres.MinConns, "POSTGRES_MAX_CONNS (%d) must not be less than POSTGRES_MIN_CONNS (%d)", c.Postgres.MaxConns, c.Postgres.MinConns)
+
+	check(c.TrashRetention >= 0, "TRASH_RETENTION must not be negative, got %s", c.TrashRetention)
+	check(c.TrashRetention == 0 || c.TrashPurgeInterval > 0, "TRASH_PURGE_INTERVAL must be positive, got %s", c.TrashPurgeInterval)
+
+	api := c.ExternalAPIs
+	switch api.Provider {
+	case "", "http":
+		for _, u := range []struct{ env, value string }{
+			{"AGE_API_URL", api.AgeURL},
+			{"GENDER_API_URL", api.GenderURL},
+			{"NATIONALITY_API_URL", api.NationalityURL},
+		} {
+			parsed, err := url.Parse(u.value)
+			check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "", "%s must be an http or https URL, got %q", u.env, u.value)
+		}
+	case "local":
+		check(api.TablePath != "", "ENRICHMENT_TABLE_PATH must not be empty for the local provider")
+	default:
+		check(false, "ENRICHMENT_PROVIDER must be http or local, got %q", api.Provider)
+	}
+	switch api.FailurePolicy {
+	case external.PolicyFail, external.PolicyNull, external.PolicyDefaults:
+	default:
+		check(false, "ENRICHMENT_FAILURE_POLICY must be fail, null or defaults, got %q", api.FailurePolicy)
+	}
+	check(api.Timeout > 0, "ENRICHMENT_TIMEOUT must be positive, got %s", api.Timeout)
+	check(!api.BatchEnabled || api.BatchWindow > 0, "ENRICHMENT_BATCH_WINDOW must be positive when batching is enabled, got %s", api.BatchWindow)
+	check(!api.CacheEnabled || api.CacheSize > 0, "ENRICHMENT_CACHE_SIZE must be positive when the cache is enabled, got %d", api.CacheSize)
+
+	check(c.Health.CacheTTL >= 0, "HEALTH_CACHE_TTL must not be negative, got %s", c.Health.CacheTTL)
+	check(c.Health.Timeout > 0, "HEALTH_TIMEOUT must be positive, got %s", c.Health.Timeout)
+
+	check(!c.Tracing.Enabled || c.Tracing.Endpoint != "", "TRACING_ENDPOINT must not be empty when tracing is enabled")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	log := c.Logger
+	_, err := zapcore.ParseLevel(log.Level)
+	check(err == nil, "LOG_LEVEL must be debug, info, warn or error, got %q", log.Level)
+	check(log.Encoding == "json" || log.Encoding == "console", "LOG_ENCODING must be json or console, got %q", log.Encoding)
+	check(len(log.OutputPaths) > 0, "LOG_OUTPUT_PATHS must not be empty")
+	check(log.SamplingInitial >= 0 && log.SamplingThereafter >= 0, "LOG_SAMPLING_INITIAL and LOG_SAMPLING_THEREAFTER must not be negative")
+	switch log.Redaction.PIIMode {
+	case logger.RedactHash, logger.RedactMask, logger.RedactDrop:
+	default:
+		check(false, "LOG_PII_MODE must be hash, mask or drop, got %q", log.Redaction.PIIMode)
+	}
+	check(log.Redaction.MaxBodyBytes >= 0, "LOG_MAX_BODY_BYTES must not be negative, got %d", log.Redaction.MaxBodyBytes)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"TestRest/pkg/postgres"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errPreconditionRequired means a write was sent without If-Match while Handlers requires it.
+var errPreconditionRequired = errors.New("If-Match header is required")
+
+// setETag marks the response with the version of p, which clients send back in If-Match on their next write.
+func setETag(w http.ResponseWriter, p *postgres.Person) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(p.Version)+`"`)
+}
+
+// ifMatch returns the person versions named by the If-Match header. It is []int{0}, which skips the version check
+// of the repository, for "*" and for a missing header unless Handlers requires one.
+func (h *Handlers) ifMatch(r *http.Request) ([]int, error) {
+	return parseIfMatch(r.Header.Get("If-Match"), h.requireIfMatch)
+}
+
+// legacyIfMatch is ifMatch for the legacy routes, whose clients predate ETags: a missing header always means any
+// version.
+func legacyIfMatch(r *http.Request) ([]int, error) {
+	return parseIfMatch(r.Header.Get("If-Match"), false)
+}
+
+// parseIfMatch returns the versions named by the strong ETags of header. If-Match compares tags strongly (RFC 9110,
+// section 13.1.1), so weak tags, like tags this service never issued, match no version and are left out; a header
+// made only of those yields no versions, which fails the precondition.
+func parseIfMatch(header string, required bool) ([]int, error) {
+	header = strings.TrimSpace(header)
+	switch {
+	case header == "" && required:
+		return nil, errPreconditionRequired
+	case header == "" || header == "*":
+		return []int{0}, nil
+	}
+
+	invalid := &paramError{param: "If-Match", message: `must be * or a list of ETags such as "3"`}
+	versions := []int{}
+	tags := 0
+	for rest := header; ; {
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			break
+		}
+		weak := strings.HasPrefix(rest, "W/")
+		rest = strings.TrimPrefix(rest, "W/")
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, invalid
+		}
+		end := strings.IndexByte(rest[1:], '"') + 1
+		if end == 0 {
+			return nil, invalid
+		}
+		tag := rest[1:end]
+		rest = strings.TrimLeft(rest[end+1:], " \t")
+		if rest != "" && rest[0] != ',' {
+			return nil, invalid
+		}
+
+		tags++
+		if version, err := strconv.Atoi(tag); err == nil && version > 0 && !weak {
+			versions = append(versions, version)
+		}
+	}
+	if tags == 0 {
+		return nil, invalid
+	}
+	return versions, nil
+}
+
+// writeIfMatch calls write with each of versions until one is the current version of the person, and returns
+// ErrVersionMismatch if none is. A write whose version check fails changes nothing, so trying the versions of an
+// If-Match list in turn matches it against the current version.
+func writeIfMatch(versions []int, write func(version int) error) error {
+	err := postgres.ErrVersionMismatch
+	for _, version := range versions {
+		if err = write(version); !errors.Is(err, postgres.ErrVersionMismatch) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"TestRest/pkg/postgres"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header     string
+		required   bool
+		want       []int
+		wantStatus int // of the problem for the error; 0 when there is none
+	}{
+		{header: "", required: true, wantStatus: http.StatusPreconditionRequired},
+		{header: "  ", required: true, wantStatus: http.StatusPreconditionRequired},
+		{header: "", want: []int{0}},
+		{header: "*", required: true, want: []int{0}},
+		{header: `"3"`, required: true, want: []int{3}},
+		{header: ` "3" `, want: []int{3}},
+		{header: `"3", "4"`, want: []int{3, 4}},
+		{header: `"3","4",`, want: []int{3, 4}},
+		{header: `W/"3"`, want: []int{}},
+		{header: `W/"3", "4"`, want: []int{4}},
+		{header: `"abc"`, want: []int{}},
+		{header: `"0", "-1"`, want: []int{}},
+		{header: `3`, wantStatus: http.StatusBadRequest},
+		{header: `"3`, wantStatus: http.StatusBadRequest},
+		{header: `"3" "4"`, wantStatus: http.StatusBadRequest},
+		{header: `"3", *`, wantStatus: http.StatusBadRequest},
+		{header: `w/"3"`, wantStatus: http.StatusBadRequest},
+		{header: `,`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, err := parseIfMatch(tt.header, tt.required)
+			if tt.wantStatus != 0 {
+				r, _ := http.NewRequest(http.MethodPut, "/people/1", nil)
+				if status := errorProblem(r, err, "").Status; err == nil || status != tt.wantStatus {
+					t.Errorf("parseIfMatch(%q) = %v, %v; want a %d problem", tt.header, got, err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIfMatch(%q) = %v, %v; want %v", tt.header, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteIfMatch(t *testing.T) {
+	const current = 4
+	errDown := errors.New("database down")
+
+	tests := []struct {
+		name      string
+		versions  []int
+		failWith  error
+		wantErr   error
+		wantTries []int
+	}{
+		{name: "any version", versions: []int{0}, wantTries: []int{0}},
+		{name: "current", versions: []int{4}, wantTries: []int{4}},
+		{name: "stale", versions: []int{3}, wantErr: postgres.ErrVersionMismatch, wantTries: []int{3}},
+		{name: "list matching", versions: []int{3, 4, 5}, wantTries: []int{3, 4}},
+		{name: "list not matching", versions: []int{2, 3}, wantErr: postgres.ErrVersionMismatch, wantTries: []int{2, 3}},
+		{name: "nothing can match", versions: []int{}, wantErr: postgres.ErrVersionMismatch},
+		{name: "other errors stop", versions: []int{3, 4}, failWith: errDown, wantErr: errDown, wantTries: []int{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tries []int
+			err := writeIfMatch(tt.versions, func(version int) error {
+				tries = append(tries, version)
+				switch {
+				case tt.failWith != nil:
+					return tt.failWith
+				case version != 0 && version != current:
+					return postgres.ErrVersionMismatch
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(tries, tt.wantTries) {
+				t.Errorf("tried %v, want %v", tries, tt.wantTries)
+			}
+		})
+	}
+}
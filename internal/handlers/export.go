@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"TestRest/pkg/logger"
+	"TestRest/pkg/postgres"
+	"encoding/csv"
+	"encoding/json"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// exportFlushEvery is how many rows are written between flushes of the response.
+const exportFlushEvery = 500
+
+// exportWriteTimeout replaces the server's write timeout for exports, which may run for much longer: it bounds the
+// time until the next flush instead of the whole response, so only a stalled client is cut off.
+const exportWriteTimeout = time.Minute
+
+var exportColumns = []string{"id", "name", "surname", "patronymic", "age", "gender", "nationality"}
+
+// exportFormats maps the format query parameter to the response content type and file extension.
+var exportFormats = map[string]struct {
+	contentType string
+	extension   string
+}{
+	"csv":    {"text/csv; charset=utf-8", "csv"},
+	"tsv":    {"text/tab-separated-values; charset=utf-8", "tsv"},
+	"ndjson": {"application/x-ndjson", "ndjson"},
+}
+
+// rowWriter writes exported people in one format.
+type rowWriter interface {
+	Header() error
+	Write(p postgres.Person) error
+	Flush() error
+}
+
+// ExportPeople streams all people matching the listing filters as a file.
+// @Summary Export people
+// @Description ExportPeople Download people as CSV, TSV or NDJSON. Accepts the same filters and sort as GET /people; without limit all matching rows are exported. The response is gzip-compressed when the client accepts it.
+// @Tags people
+// @Produce text/csv
+// @Produce text/tab-separated-values
+// @Produce application/x-ndjson
+// @Param format query string false "csv, tsv or ndjson" default(csv)
+// @Param name query string false "Name prefix (case-insensitive)"
+// @Param surname query string false "Surname prefix (case-insensitive)"
+// @Param patronymic query string false "Patronymic prefix (case-insensitive)"
+// @Param age query int false "Exact age"
+// @Param age_gte query int false "Minimum age"
+// @Param age_lte query int false "Maximum age"
+// @Param gender query []string false "Allowed genders" collectionFormat(csv)
+// @Param nationality query []string false "Allowed nationalities" collectionFormat(csv)
+// @Param sort query string false "Sort fields, '-' prefix for descending, e.g. -age,surname"
+// @Param limit query int false "Maximum number of rows"
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of a listing page to start after"
+// @Success 200 {file} file "Exported people"
+// @Failure 400 {object} Problem "Invalid query parameter"
+// @Failure 500 {object} Problem "Failed to export people"
+// @Router /people:export [get]
+func (h *Handlers) ExportPeople(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	formatName := query.Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+	format, ok := exportFormats[formatName]
+	if !ok {
+		writeError(w, r, &paramError{param: "format", message: "use csv, tsv or ndjson"}, "Invalid query parameter")
+		return
+	}
+
+	filter, err := parseListFilter(query)
+	if err != nil {
+		writeError(w, r, err, "Invalid query parameter")
+		return
+	}
+	if query.Get("limit") == "" {
+		filter.Limit = 0
+	}
+
+	out := newRowWriter(formatName, w)
+	flusher, _ := w.(http.Flusher)
+	controller := http.NewResponseController(w)
+	extendDeadline := func() {
+		// Writers that cannot set deadlines, e.g. in tests, have no server deadline to extend either.
+		_ = controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	}
+	extendDeadline()
+
+	// The response starts with the first row, so that errors found before it can still be reported properly.
+	started := false
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", format.contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="people.`+format.extension+`"`)
+		w.WriteHeader(http.StatusOK)
+		return out.Header()
+	}
+
+	rows := 0
+	err = h.people.Export(r.Context(), filter, func(p postgres.Person) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := out.Write(p); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			extendDeadline()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = out.Flush()
+	}
+
+	if err != nil {
+		if !started {
+			writeError(w, r, err, "Failed to export people")
+			return
+		}
+		// The status line is already sent; all we can do is cut the response short.
+		logger.GetLoggerFromContext(r.Context()).Warn(r.Context(), "Export aborted", zap.Int("rows", rows), zap.Error(err))
+	}
+}
+
+func newRowWriter(format string, w io.Writer) rowWriter {
+	switch format {
+	case "ndjson":
+		return &ndjsonRowWriter{enc: json.NewEncoder(w)}
+	case "tsv":
+		cw := csv.NewWriter(w)
+		cw.Comma = '\t'
+		return &csvRowWriter{w: cw}
+	default:
+		return &csvRowWriter{w: csv.NewWriter(w)}
+	}
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) Header() error {
+	return c.w.Write(exportColumns)
+}
+
+func (c *csvRowWriter) Write(p postgres.Person) error {
+	age := ""
+	if p.Age != nil {
+		age = strconv.Itoa(*p.Age)
+	}
+	return c.w.Write([]string{strconv.Itoa(p.ID), p.Name, p.Surname, p.Patronymic, age, optional(p.Gender), optional(p.Nationality)})
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonRowWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonRowWriter) Header() error {
+	return nil
+}
+
+func (n *ndjsonRowWriter) Write(p postgres.Person) error {
+	return n.enc.Encode(p)
+}
+
+func (n *ndjsonRowWriter) Flush() error {
+	return nil
+}
+
+func optional(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -1,254 +1,474 @@
 package handlers
 
 // Package handlers provides HTTP handler functions for the REST API.
-// It includes handlers for CRUD operations on the "people" table and initializes the database connection for handlers.
+// It includes handlers for CRUD operations on the "people" table, backed by a postgres.PeopleRepository.
 
 import (
 	"TestRest/external"
+	"TestRest/internal/validation"
 	"TestRest/pkg/postgres"
-	"context"
 	"encoding/json"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var db *pgxpool.Pool
-var ctx context.Context
+// Handlers serves the people API on top of a repository and an enrichment client.
+type Handlers struct {
+	people     postgres.PeopleRepository
+	enrichment *external.Client
+	// requireIfMatch rejects updates and deletes that do not name the version they overwrite.
+	requireIfMatch bool
+}
 
-func InitHandlers(database *pgxpool.Pool, context context.Context) {
-	db = database
-	ctx = context
+func New(people postgres.PeopleRepository, enrichment *external.Client, requireIfMatch bool) *Handlers {
+	return &Handlers{people: people, enrichment: enrichment, requireIfMatch: requireIfMatch}
 }
 
-// GetInfo retrieves a person's information by ID.
-// @Summary Get person info
-// @Description GetInfo Get a person's details by their ID
+// ListPeople returns a page of people matching the query parameters.
+// @Summary List people
+// @Description ListPeople Get people with filtering, sorting and limit/offset or cursor pagination
 // @Tags people
-// @Accept json
 // @Produce json
-// @Param id query int true "Person ID"
-// @Success 200 {object} postgres.Person
-// @Failure 400 {string} string "Invalid ID parameter"
-// @Failure 500 {string} string "Failed to get person"
-// @Router /get [get]
-func GetInfo(w http.ResponseWriter, r *http.Request) {
-	var params struct {
-		ID          int    `json:"id"`
-		Name        string `json:"name"`
-		Surname     string `json:"surname"`
-		Patronymic  string `json:"patronymic"`
-		Age         int    `json:"age"`
-		Gender      string `json:"gender"`
-		Nationality string `json:"nationality"`
+// @Param name query string false "Name prefix (case-insensitive)"
+// @Param surname query string false "Surname prefix (case-insensitive)"
+// @Param patronymic query string false "Patronymic prefix (case-insensitive)"
+// @Param age query int false "Exact age"
+// @Param age_gte query int false "Minimum age"
+// @Param age_lte query int false "Maximum age"
+// @Param gender query []string false "Allowed genders" collectionFormat(csv)
+// @Param nationality query []string false "Allowed nationalities" collectionFormat(csv)
+// @Param sort query string false "Sort fields, '-' prefix for descending, e.g. -age,surname"
+// @Param limit query int false "Page size" default(50)
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} postgres.PersonPage
+// @Failure 400 {object} Problem "Invalid query parameter"
+// @Failure 500 {object} Problem "Failed to get people"
+// @Router /people [get]
+func (h *Handlers) ListPeople(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err, "Invalid query parameter")
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+
+	page, err := h.people.List(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, err, "Failed to get people")
 		return
 	}
 
-	person, err := postgres.GetPerson(ctx, db, params.ID, params.Name, params.Surname, params.Patronymic, params.Age, params.Gender, params.Nationality)
+	writeJSON(w, r, http.StatusOK, page)
+}
+
+// GetPerson retrieves a single person by ID, as it is now or as it was at the as_of instant.
+// @Summary Get person
+// @Description GetPerson Get a person's details by their ID
+// @Tags people
+// @Produce json
+// @Param id path int true "Person ID"
+// @Param as_of query string false "RFC 3339 instant to read the person as of, e.g. 2026-01-01T00:00:00Z"
+// @Success 200 {object} postgres.Person
+// @Header 200 {string} ETag "Version of the person, for If-Match"
+// @Failure 400 {object} Problem "Invalid ID parameter"
+// @Failure 404 {object} Problem "Person not found"
+// @Failure 500 {object} Problem "Failed to get person"
+// @Router /people/{id} [get]
+func (h *Handlers) GetPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := personID(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Failed to get person"))
+		writeError(w, r, err, "Invalid ID parameter")
 		return
 	}
 
-	response, err := json.Marshal(person)
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		at, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			writeError(w, r, &paramError{param: "as_of", message: "must be an RFC 3339 timestamp"}, "Invalid as_of parameter")
+			return
+		}
+
+		// A past version cannot be written back with If-Match, so it gets no ETag.
+		person, err := h.people.GetAsOf(r.Context(), id, at)
+		if err != nil {
+			writeError(w, r, err, "Failed to get person")
+			return
+		}
+		writeJSON(w, r, http.StatusOK, person)
+		return
+	}
+
+	person, err := h.people.Get(r.Context(), id)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Failed to process person data"))
+		writeError(w, r, err, "Failed to get person")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(response)
+	setETag(w, person)
+	writeJSON(w, r, http.StatusOK, person)
 }
 
-// DeletePerson deletes a person by ID.
-// @Summary Delete person
-// @Description DeletePerson Delete a person by their ID
+// CreatePerson enriches and inserts a new person.
+// @Summary Create person
+// @Description CreatePerson Add a new person; age, gender and nationality are fetched from external APIs
 // @Tags people
-// @Param id query int true "Person ID"
-// @Success 200 {string} string "Deleted person by ID"
-// @Failure 400 {string} string "Invalid ID parameter"
-// @Failure 500 {string} string "Failed to delete person"
-// @Router /delete [delete]
-func DeletePerson(w http.ResponseWriter, r *http.Request) {
-	var params struct {
-		ID int `json:"id"`
-	}
+// @Accept json
+// @Produce json
+// @Param person body CreatePersonRequest true "Person to create"
+// @Success 201 {object} postgres.Person
+// @Header 201 {string} ETag "Version of the person, for If-Match"
+// @Failure 400 {object} Problem "Invalid request body"
+// @Failure 422 {object} Problem "Invalid person"
+// @Failure 500 {object} Problem "Failed to insert person"
+// @Router /people [post]
+func (h *Handlers) CreatePerson(w http.ResponseWriter, r *http.Request) {
+	var params CreatePersonRequest
 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	p := postgres.Person{Name: params.Name, Surname: params.Surname, Patronymic: params.Patronymic}
+	validation.Normalize(&p)
+	if err := validation.Person(p); err != nil {
+		writeError(w, r, err, "Invalid person")
 		return
 	}
 
-	_, err := postgres.GetPerson(ctx, db, params.ID, "", "", "", 0, "", "")
+	enrichment, err := h.enrichment.Enrich(r.Context(), p.Name)
 	if err != nil {
-		if err.Error() == "no person found" {
-			http.Error(w, "Person not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to retrieve person", http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to insert person")
 		return
 	}
 
-	err = postgres.DeletePerson(ctx, db, params.ID)
+	person, err := h.people.Create(r.Context(), enrichedPerson(p.Name, p.Surname, p.Patronymic, enrichment))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Failed to delete person"))
+		writeError(w, r, err, "Failed to insert person")
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Deleted person by id: " + string(params.ID)))
+	w.Header().Set("Location", fmt.Sprintf("/people/%d", person.ID))
+	setETag(w, person)
+	writeJSON(w, r, http.StatusCreated, person)
 }
 
-// InsertPerson inserts a new person into the database.
-// @Summary Insert person
-// @Description InsertPerson Add a new person to the database
+// UpdatePerson replaces all editable fields of a person.
+// @Summary Replace person
+// @Description UpdatePerson Replace a person's details by their ID
 // @Tags people
-// @Param name query string true "Person's name"
-// @Param surname query string true "Person's surname"
-// @Param patronymic query string false "Person's patronymic"
+// @Accept json
+// @Produce json
+// @Param id path int true "Person ID"
+// @Param If-Match header string false "ETag of the person being replaced, or a list of ETags any of which may match; required unless REQUIRE_IF_MATCH=false"
+// @Param person body UpdatePersonRequest true "New person details"
 // @Success 200 {object} postgres.Person
-// @Failure 500 {string} string "Failed to insert person"
-// @Router /post [post]
-func InsertPerson(w http.ResponseWriter, r *http.Request) {
-	var params struct {
-		Name       string `json:"name"`
-		Surname    string `json:"surname"`
-		Patronymic string `json:"patronymic"`
+// @Header 200 {string} ETag "New version of the person"
+// @Failure 400 {object} Problem "Invalid ID parameter"
+// @Failure 404 {object} Problem "Person not found"
+// @Failure 412 {object} Problem "Person was changed since it was read"
+// @Failure 422 {object} Problem "Invalid person"
+// @Failure 428 {object} Problem "If-Match header missing"
+// @Failure 500 {object} Problem "Failed to update person"
+// @Router /people/{id} [put]
+func (h *Handlers) UpdatePerson(w http.ResponseWriter, r *http.Request) {
+	id, err := personID(r)
+	if err != nil {
+		writeError(w, r, err, "Invalid ID parameter")
+		return
 	}
+
+	versions, err := h.ifMatch(r)
+	if err != nil {
+		writeError(w, r, err, "Failed to update person")
+		return
+	}
+
+	var params UpdatePersonRequest
 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid request body: "+err.Error())
 		return
 	}
 
-	name := params.Name
-	surname := params.Surname
-	patronymic := params.Patronymic
-	age, err := external.GetAge(name)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Failed to insert person - unable to fetch age"))
+	p := postgres.Person{
+		ID:          id,
+		Name:        params.Name,
+		Surname:     params.Surname,
+		Patronymic:  params.Patronymic,
+		Age:         params.Age,
+		Gender:      params.Gender,
+		Nationality: params.Nationality,
+	}
+	validation.Normalize(&p)
+	if err := validation.Person(p); err != nil {
+		writeError(w, r, err, "Invalid person")
 		return
 	}
-	gender, err := external.GetGender(name)
+
+	var person *postgres.Person
+	err = writeIfMatch(versions, func(version int) (err error) {
+		p.Version = version
+		person, err = h.people.Update(r.Context(), p)
+		return err
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Failed to insert person - unable to fetch gender"))
+		writeError(w, r, err, "Failed to update person")
 		return
 	}
-	nationality, err := external.GetNationality(name)
+
+	setETag(w, person)
+	writeJSON(w, r, http.StatusOK, person)
+}
+
+// PatchPerson applies a JSON Merge Patch (RFC 7396) to a person: members present in the body replace their fields,
+// null clears them, and absent members are left unchanged.
+// @Summary Patch person
+// @Description PatchPerson Update some of a person's details by their ID. null clears age, gender, nationality or patronymic.
+// @Tags people
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path int true "Person ID"
+// @Param If-Match header string false "ETag of the person being patched, or a list of ETags any of which may match; required unless REQUIRE_IF_MATCH=false"
+// @Param person body PatchPersonRequest true "Fields to change"
+// @Success 200 {object} postgres.Person
+// @Header 200 {string} ETag "New version of the person"
+// @Failure 400 {object} Problem "Invalid ID parameter"
+// @Failure 404 {object} Problem "Person not found"
+// @Failure 412 {object} Problem "Person was changed since it was read"
+// @Failure 415 {object} Problem "Unsupported patch format"
+// @Failure 422 {object} Problem "Invalid person"
+// @Failure 428 {object} Problem "If-Match header missing"
+// @Failure 500 {object} Problem "Failed to update person"
+// @Router /people/{id} [patch]
+func (h *Handlers) PatchPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := personID(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Failed to insert person - unable to fetch nationality"))
+		writeError(w, r, err, "Invalid ID parameter")
 		return
 	}
 
-	person, err := postgres.InsertPerson(ctx, db, name, surname, patronymic, age, gender, nationality)
+	versions, err := h.ifMatch(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Failed to insert person - error in database"))
+		writeError(w, r, err, "Failed to update person")
 		return
 	}
 
-	response, err := json.Marshal(person)
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+			writeProblem(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Unsupported patch format: use application/merge-patch+json")
+			return
+		}
+	}
+
+	var params PatchPersonRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&params); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	patch := postgres.PersonPatch{
+		Name:        params.Name,
+		Surname:     params.Surname,
+		Patronymic:  params.Patronymic,
+		Age:         params.Age,
+		Gender:      params.Gender,
+		Nationality: params.Nationality,
+	}
+	validation.NormalizePatch(&patch)
+	if err := validation.Patch(patch); err != nil {
+		writeError(w, r, err, "Invalid person")
+		return
+	}
+
+	var person *postgres.Person
+	err = writeIfMatch(versions, func(version int) (err error) {
+		person, err = h.people.Patch(r.Context(), id, version, patch)
+		return err
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Failed to process person data"))
+		writeError(w, r, err, "Failed to update person")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(response)
+	setETag(w, person)
+	writeJSON(w, r, http.StatusOK, person)
 }
 
-// UpdatePerson updates an existing person's information.
-// @Summary Update person
-// @Description UpdatePerson Update a person's details by their ID
+// DeletePerson moves a person to the trash.
+// @Summary Delete person
+// @Description DeletePerson Move a person to the trash; it can be restored until it is purged
 // @Tags people
-// @Param id query int true "Person ID"
-// @Param name query string true "Person's name"
-// @Param surname query string true "Person's surname"
-// @Param patronymic query string false "Person's patronymic"
-// @Success 200 {object} postgres.Person
-// @Failure 400 {string} string "Invalid ID parameter"
-// @Failure 500 {string} string "Failed to update person"
-// @Router /put [put]
-func UpdatePerson(w http.ResponseWriter, r *http.Request) {
-	var params struct {
-		ID          int    `json:"id"`
-		Name        string `json:"name"`
-		Surname     string `json:"surname"`
-		Patronymic  string `json:"patronymic"`
-		Age         int    `json:"age"`
-		Gender      string `json:"gender"`
-		Nationality string `json:"nationality"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+// @Param id path int true "Person ID"
+// @Param If-Match header string false "ETag of the person being deleted, or a list of ETags any of which may match; required unless REQUIRE_IF_MATCH=false"
+// @Success 204 "Person deleted"
+// @Failure 400 {object} Problem "Invalid ID parameter"
+// @Failure 404 {object} Problem "Person not found"
+// @Failure 412 {object} Problem "Person was changed since it was read"
+// @Failure 428 {object} Problem "If-Match header missing"
+// @Failure 500 {object} Problem "Failed to delete person"
+// @Router /people/{id} [delete]
+func (h *Handlers) DeletePerson(w http.ResponseWriter, r *http.Request) {
+	id, err := personID(r)
+	if err != nil {
+		writeError(w, r, err, "Invalid ID parameter")
 		return
 	}
 
-	id := params.ID
-
-	persons, err := postgres.GetPerson(ctx, db, id, "", "", "", 0, "", "")
+	versions, err := h.ifMatch(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Failed to retrieve person"))
+		writeError(w, r, err, "Failed to delete person")
 		return
 	}
 
-	if len(persons) != 1 {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid ID or multiple persons found"))
+	err = writeIfMatch(versions, func(version int) error {
+		return h.people.Delete(r.Context(), id, version)
+	})
+	if err != nil {
+		writeError(w, r, err, "Failed to delete person")
 		return
 	}
-	p := persons[0]
 
-	name := params.Name
-	if name == "" {
-		name = p.Name
-	}
-	surname := params.Surname
-	if surname == "" {
-		surname = p.Surname
+	w.WriteHeader(http.StatusNoContent)
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+// parseListFilter reads listing filters, sorting and pagination from the query string.
+func parseListFilter(query url.Values) (postgres.ListFilter, error) {
+	filter := postgres.ListFilter{
+		Name:          query.Get("name"),
+		Surname:       query.Get("surname"),
+		Patronymic:    query.Get("patronymic"),
+		Genders:       listParam(query, "gender"),
+		Nationalities: listParam(query, "nationality"),
+		Cursor:        query.Get("cursor"),
+		Limit:         defaultPageSize,
 	}
-	patronymic := params.Patronymic
-	if patronymic == "" {
-		patronymic = p.Patronymic
+
+	ints := []struct {
+		name string
+		dst  **int
+	}{
+		{"age", &filter.Age},
+		{"age_gte", &filter.AgeGTE},
+		{"age_lte", &filter.AgeLTE},
 	}
-	age := params.Age
-	if age == 0 {
-		age = p.Age
+	for _, p := range ints {
+		if v := query.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return filter, &paramError{param: p.name, message: "must be an integer"}
+			}
+			*p.dst = &n
+		}
 	}
-	gender := params.Gender
-	if gender == "" {
-		gender = p.Gender
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			return filter, &paramError{param: "limit", message: fmt.Sprintf("must be between 1 and %d", maxPageSize)}
+		}
+		filter.Limit = n
 	}
-	nationality := params.Nationality
-	if nationality == "" {
-		nationality = p.Nationality
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return filter, &paramError{param: "offset", message: "must be a non-negative integer"}
+		}
+		filter.Offset = n
 	}
 
-	updatedPerson, err := postgres.UpdatePerson(ctx, db, id, name, surname, patronymic, age, gender, nationality)
+	sort, err := postgres.ParseSort(query.Get("sort"))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Failed to update person"))
-		return
+		return filter, &paramError{param: "sort", message: err.Error()}
 	}
+	filter.Sort = sort
+
+	return filter, nil
+}
+
+// listParam collects a multi-valued query parameter given either repeated (gender=m&gender=f) or comma-separated.
+func listParam(query url.Values, name string) []string {
+	var values []string
+	for _, v := range query[name] {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+type CreatePersonRequest struct {
+	Name       string `json:"name"`
+	Surname    string `json:"surname"`
+	Patronymic string `json:"patronymic"`
+}
+
+type UpdatePersonRequest struct {
+	Name        string  `json:"name"`
+	Surname     string  `json:"surname"`
+	Patronymic  string  `json:"patronymic"`
+	Age         *int    `json:"age"`
+	Gender      *string `json:"gender"`
+	Nationality *string `json:"nationality"`
+}
+
+// PatchPersonRequest is a JSON Merge Patch of a person; every member is optional and null clears the field.
+type PatchPersonRequest struct {
+	Name        postgres.PatchField[string] `json:"name" swaggertype:"string"`
+	Surname     postgres.PatchField[string] `json:"surname" swaggertype:"string"`
+	Patronymic  postgres.PatchField[string] `json:"patronymic" swaggertype:"string"`
+	Age         postgres.PatchField[int]    `json:"age" swaggertype:"integer"`
+	Gender      postgres.PatchField[string] `json:"gender" swaggertype:"string"`
+	Nationality postgres.PatchField[string] `json:"nationality" swaggertype:"string"`
+}
+
+// personID parses the {id} URL parameter.
+func personID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		return 0, &paramError{param: "id", message: "must be a positive integer"}
+	}
+	return id, nil
+}
+
+// enrichedPerson builds a new person from the request fields and the enrichment result.
+func enrichedPerson(name, surname, patronymic string, enrichment *external.Result) postgres.Person {
+	p := postgres.Person{Name: name, Surname: surname, Patronymic: patronymic}
+	if enrichment.Age != nil {
+		p.Age = &enrichment.Age.Age
+	}
+	if enrichment.Gender != nil {
+		p.Gender = &enrichment.Gender.Gender
+	}
+	if enrichment.Nationality != nil {
+		p.Nationality = &enrichment.Nationality.CountryID
+	}
+	return p
+}
 
-	response, err := json.Marshal(updatedPerson)
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	response, err := json.Marshal(v)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Failed to process person data"))
+		writeProblem(w, r, http.StatusInternalServerError, CodeInternal, "Failed to process person data")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 	w.Write(response)
 }
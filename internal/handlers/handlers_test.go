@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"TestRest/pkg/logger/loggertest"
+	"TestRest/pkg/postgres"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryPeople is a PeopleRepository kept in a map, with the version checks and the trash of the PostgreSQL one.
+// Methods the tests do not need panic through the nil embedded interface.
+type memoryPeople struct {
+	postgres.PeopleRepository
+
+	mu     sync.Mutex
+	people map[int]postgres.Person
+	nextID int
+	// createManyErr, when set, makes CreateMany fail without storing anything.
+	createManyErr error
+}
+
+// newMemoryPeople stores people with IDs from 1 and, unless they have one, version 1.
+func newMemoryPeople(people ...postgres.Person) *memoryPeople {
+	m := &memoryPeople{people: map[int]postgres.Person{}}
+	for _, p := range people {
+		m.add(p)
+	}
+	return m
+}
+
+func (m *memoryPeople) add(p postgres.Person) postgres.Person {
+	m.nextID++
+	p.ID = m.nextID
+	if p.Version == 0 {
+		p.Version = 1
+	}
+	m.people[p.ID] = p
+	return p
+}
+
+func (m *memoryPeople) Create(_ context.Context, p postgres.Person) (*postgres.Person, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p = m.add(p)
+	return &p, nil
+}
+
+func (m *memoryPeople) CreateMany(_ context.Context, people []postgres.Person) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.createManyErr != nil {
+		return 0, m.createManyErr
+	}
+	for _, p := range people {
+		m.add(p)
+	}
+	return int64(len(people)), nil
+}
+
+func (m *memoryPeople) Get(_ context.Context, id int) (*postgres.Person, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.people[id]
+	if !ok || p.DeletedAt != nil {
+		return nil, postgres.ErrNotFound
+	}
+	return &p, nil
+}
+
+func (m *memoryPeople) Update(_ context.Context, p postgres.Person) (*postgres.Person, error) {
+	return m.write(p.ID, p.Version, false, func(person *postgres.Person) {
+		person.Name, person.Surname, person.Patronymic = p.Name, p.Surname, p.Patronymic
+		person.Age, person.Gender, person.Nationality = p.Age, p.Gender, p.Nationality
+	})
+}
+
+func (m *memoryPeople) Patch(ctx context.Context, id, version int, patch postgres.PersonPatch) (*postgres.Person, error) {
+	if patch.Empty() {
+		person, err := m.Get(ctx, id)
+		if err == nil && version != 0 && person.Version != version {
+			return nil, postgres.ErrVersionMismatch
+		}
+		return person, err
+	}
+	return m.write(id, version, false, func(person *postgres.Person) {
+		for _, f := range []struct {
+			field postgres.PatchField[string]
+			dst   *string
+		}{
+			{patch.Name, &person.Name},
+			{patch.Surname, &person.Surname},
+			{patch.Patronymic, &person.Patronymic},
+		} {
+			if f.field.Set {
+				*f.dst = ""
+				if f.field.Value != nil {
+					*f.dst = *f.field.Value
+				}
+			}
+		}
+		if patch.Age.Set {
+			person.Age = patch.Age.Value
+		}
+		if patch.Gender.Set {
+			person.Gender = patch.Gender.Value
+		}
+		if patch.Nationality.Set {
+			person.Nationality = patch.Nationality.Value
+		}
+	})
+}
+
+func (m *memoryPeople) Delete(_ context.Context, id, version int) error {
+	_, err := m.write(id, version, false, func(person *postgres.Person) {
+		now := time.Now()
+		person.DeletedAt = &now
+	})
+	return err
+}
+
+func (m *memoryPeople) Restore(_ context.Context, id, version int) (*postgres.Person, error) {
+	return m.write(id, version, true, func(person *postgres.Person) {
+		person.DeletedAt = nil
+	})
+}
+
+// write applies change to person id, which must be in the trash if trashed and out of it otherwise, and increments
+// its version. A non-zero version must be the current one.
+func (m *memoryPeople) write(id, version int, trashed bool, change func(*postgres.Person)) (*postgres.Person, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.people[id]
+	if !ok || (p.DeletedAt != nil) != trashed {
+		return nil, postgres.ErrNotFound
+	}
+	if version != 0 && p.Version != version {
+		return nil, postgres.ErrVersionMismatch
+	}
+	change(&p)
+	p.Version++
+	m.people[id] = p
+	return &p, nil
+}
+
+// newTestRouter routes the /people endpoints to New(people, nil, true) like cmd/main.go does.
+func newTestRouter(people postgres.PeopleRepository) http.Handler {
+	h := New(people, nil, true)
+	router := chi.NewRouter()
+	router.Route("/people/{id}", func(r chi.Router) {
+		r.Get("/", h.GetPerson)
+		r.Put("/", h.UpdatePerson)
+		r.Patch("/", h.PatchPerson)
+		r.Delete("/", h.DeletePerson)
+		r.Post("/restore", h.RestorePerson)
+	})
+	router.Post("/people:import", h.ImportPeople)
+	return router
+}
+
+// serve sends a request with body and, unless it is empty, If-Match to router.
+func serve(router http.Handler, method, target, ifMatch, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if ifMatch != "" {
+		r.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func problemCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("body %q: %v", w.Body.String(), err)
+	}
+	return problem.Code
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestGetPerson(t *testing.T) {
+	router := newTestRouter(newMemoryPeople(postgres.Person{Name: "Ivan", Surname: "Ivanov", Version: 3}))
+
+	w := serve(router, http.MethodGet, "/people/1", "", "")
+	var person postgres.Person
+	if err := json.Unmarshal(w.Body.Bytes(), &person); err != nil {
+		t.Fatalf("body %q: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusOK || person.Name != "Ivan" || person.Version != 3 {
+		t.Errorf("got %d %+v, want person 1 at version 3", w.Code, person)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"3"` {
+		t.Errorf("ETag = %s, want \"3\"", etag)
+	}
+
+	tests := []struct {
+		target     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"/people/2", http.StatusNotFound, CodeNotFound},
+		{"/people/abc", http.StatusBadRequest, CodeInvalidParameter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := serve(router, http.MethodGet, tt.target, "", "")
+			if code := problemCode(t, w); w.Code != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("got %d %s, want %d %s", w.Code, code, tt.wantStatus, tt.wantCode)
+			}
+			if etag := w.Header().Get("ETag"); etag != "" {
+				t.Errorf("ETag = %s on an error", etag)
+			}
+		})
+	}
+}
+
+func TestConditionalWrites(t *testing.T) {
+	requests := []struct {
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{http.MethodPut, `{"name":"Petr","surname":"Petrov"}`, http.StatusOK},
+		{http.MethodPatch, `{"name":"Petr"}`, http.StatusOK},
+		{http.MethodDelete, "", http.StatusNoContent},
+	}
+	tests := []struct {
+		name       string
+		target     string
+		ifMatch    string
+		wantStatus int // 0 for the success status of the method
+		wantCode   string
+	}{
+		{name: "current", target: "/people/1", ifMatch: `"2"`},
+		{name: "any", target: "/people/1", ifMatch: "*"},
+		{name: "list", target: "/people/1", ifMatch: `"1", "2"`},
+		{name: "missing", target: "/people/1", wantStatus: http.StatusPreconditionRequired, wantCode: CodePreconditionRequired},
+		{name: "stale", target: "/people/1", ifMatch: `"1"`, wantStatus: http.StatusPreconditionFailed, wantCode: CodePreconditionFailed},
+		{name: "weak", target: "/people/1", ifMatch: `W/"2"`, wantStatus: http.StatusPreconditionFailed, wantCode: CodePreconditionFailed},
+		{name: "malformed", target: "/people/1", ifMatch: "2", wantStatus: http.StatusBadRequest, wantCode: CodeInvalidParameter},
+		{name: "unknown person", target: "/people/9", ifMatch: `"2"`, wantStatus: http.StatusNotFound, wantCode: CodeNotFound},
+	}
+	for _, req := range requests {
+		for _, tt := range tests {
+			t.Run(req.method+" "+tt.name, func(t *testing.T) {
+				people := newMemoryPeople(postgres.Person{Name: "Ivan", Surname: "Ivanov", Version: 2})
+				w := serve(newTestRouter(people), req.method, tt.target, tt.ifMatch, req.body)
+
+				stored := people.people[1]
+				if tt.wantStatus != 0 {
+					if code := problemCode(t, w); w.Code != tt.wantStatus || code != tt.wantCode {
+						t.Errorf("got %d %s, want %d %s", w.Code, code, tt.wantStatus, tt.wantCode)
+					}
+					if stored.Version != 2 {
+						t.Errorf("person changed to version %d by a failed write", stored.Version)
+					}
+					return
+				}
+				if w.Code != req.wantStatus || stored.Version != 3 {
+					t.Errorf("got %d with the person at version %d, want %d and version 3", w.Code, stored.Version, req.wantStatus)
+				}
+				if etag := w.Header().Get("ETag"); req.method != http.MethodDelete && etag != `"3"` {
+					t.Errorf("ETag = %s, want \"3\"", etag)
+				}
+			})
+		}
+	}
+}
+
+func TestPatchPerson(t *testing.T) {
+	ivan := postgres.Person{
+		ID:          1,
+		Name:        "Ivan",
+		Surname:     "Ivanov",
+		Patronymic:  "Ivanovich",
+		Age:         ptr(30),
+		Gender:      ptr("m"),
+		Nationality: ptr("RU"),
+		Version:     1,
+	}
+	patched := func(change func(p *postgres.Person)) postgres.Person {
+		p := ivan
+		p.Version = 2
+		change(&p)
+		return p
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		want       postgres.Person
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "null age", body: `{"age":null}`, want: patched(func(p *postgres.Person) { p.Age = nil })},
+		{name: "null patronymic", body: `{"patronymic":null}`, want: patched(func(p *postgres.Person) { p.Patronymic = "" })},
+		{name: "value", body: `{"gender":"f","nationality":null}`, want: patched(func(p *postgres.Person) {
+			p.Gender = ptr("f")
+			p.Nationality = nil
+		})},
+		{name: "empty", body: `{}`, want: ivan},
+		{name: "null name", body: `{"name":null}`, wantStatus: http.StatusUnprocessableEntity, wantCode: CodeValidationFailed},
+		{name: "unknown member", body: `{"nickname":"Vanya"}`, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidBody},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			people := newMemoryPeople(ivan)
+			w := serve(newTestRouter(people), http.MethodPatch, "/people/1", `"1"`, tt.body)
+
+			if tt.wantStatus != 0 {
+				if code := problemCode(t, w); w.Code != tt.wantStatus || code != tt.wantCode {
+					t.Errorf("got %d %s, want %d %s", w.Code, code, tt.wantStatus, tt.wantCode)
+				}
+				if !reflect.DeepEqual(people.people[1], ivan) {
+					t.Errorf("person changed to %+v by a failed patch", people.people[1])
+				}
+				return
+			}
+			var got postgres.Person
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("body %q: %v", w.Body.String(), err)
+			}
+			if w.Code != http.StatusOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %d %+v, want %+v", w.Code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteAndRestorePerson(t *testing.T) {
+	router := newTestRouter(newMemoryPeople(postgres.Person{Name: "Ivan", Surname: "Ivanov"}))
+
+	steps := []struct {
+		method     string
+		target     string
+		ifMatch    string
+		wantStatus int
+	}{
+		{http.MethodPost, "/people/1/restore", `"1"`, http.StatusNotFound},
+		{http.MethodDelete, "/people/1", `"1"`, http.StatusNoContent},
+		{http.MethodGet, "/people/1", "", http.StatusNotFound},
+		{http.MethodDelete, "/people/1", `"2"`, http.StatusNotFound},
+		{http.MethodPost, "/people/1/restore", "", http.StatusPreconditionRequired},
+		{http.MethodPost, "/people/1/restore", `"1"`, http.StatusPreconditionFailed},
+		{http.MethodPost, "/people/1/restore", `"2"`, http.StatusOK},
+		{http.MethodGet, "/people/1", "", http.StatusOK},
+	}
+	for i, step := range steps {
+		w := serve(router, step.method, step.target, step.ifMatch, "")
+		if w.Code != step.wantStatus {
+			t.Fatalf("step %d: %s %s with If-Match %s: got %d, want %d", i+1, step.method, step.target, step.ifMatch, w.Code, step.wantStatus)
+		}
+	}
+}
+
+func TestImportPeopleConflict(t *testing.T) {
+	people := newMemoryPeople()
+	people.createManyErr = fmt.Errorf("failed to insert people: %w", postgres.ErrConflict)
+	ctx, logs := loggertest.NewObserved(context.Background())
+
+	// The first row needs no enrichment and the second is rejected, so only CreateMany fails.
+	body := "name,surname,age,gender,nationality\nIvan,Ivanov,30,m,RU\nPetr,,,,\n"
+	r := httptest.NewRequest(http.MethodPost, "/people:import", strings.NewReader(body)).WithContext(ctx)
+	r.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	newTestRouter(people).ServeHTTP(w, r)
+
+	var problem ImportProblem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("body %q: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusConflict || problem.Code != CodeConflict {
+		t.Errorf("got %d %+v, want a conflict problem", w.Code, problem.Problem)
+	}
+	if problem.Report.Accepted != 0 || problem.Report.Rejected != 1 || problem.Report.RejectedLines[0].Line != 3 {
+		t.Errorf("report = %+v, want line 3 rejected and nothing accepted", problem.Report)
+	}
+	if entries := logs.FilterMessage("Import aborted").All(); len(entries) != 1 || !strings.Contains(entries[0].ContextMap()["error"].(string), "conflict") {
+		t.Errorf("logged %v, want the import aborted with the conflict", entries)
+	}
+}
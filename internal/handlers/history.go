@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"TestRest/pkg/postgres"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"strconv"
+)
+
+// ActorHeader names the client making a change, recorded with it in the audit log.
+const ActorHeader = "X-Actor"
+
+// Audit attributes the writes of a request to the actor in ActorHeader and the request ID. It must run after
+// middleware.RequestID.
+func Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := postgres.WithAuditInfo(r.Context(), postgres.AuditInfo{
+			Actor:     r.Header.Get(ActorHeader),
+			RequestID: middleware.GetReqID(r.Context()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetPersonHistory returns every recorded change to a person.
+// @Summary Get person history
+// @Description GetPersonHistory Get the audit log of a person, oldest change first, including deletes and purges
+// @Tags people
+// @Produce json
+// @Param id path int true "Person ID"
+// @Success 200 {array} postgres.AuditEntry
+// @Failure 400 {object} Problem "Invalid ID parameter"
+// @Failure 404 {object} Problem "Person not found"
+// @Failure 500 {object} Problem "Failed to get person history"
+// @Router /people/{id}/history [get]
+func (h *Handlers) GetPersonHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := personID(r)
+	if err != nil {
+		writeError(w, r, err, "Invalid ID parameter")
+		return
+	}
+
+	entries, err := h.people.History(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "Failed to get person history")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, entries)
+}
+
+// RevertPerson makes an earlier version of a person current again.
+// @Summary Revert person
+// @Description RevertPerson Write a new version of a person with the fields of an earlier version
+// @Tags people
+// @Produce json
+// @Param id path int true "Person ID"
+// @Param to_version query int true "Version to restore the fields of"
+// @Param If-Match header string false "ETag of the person being reverted, or a list of ETags any of which may match; required unless REQUIRE_IF_MATCH=false"
+// @Success 200 {object} postgres.Person
+// @Header 200 {string} ETag "New version of the person"
+// @Failure 400 {object} Problem "Invalid parameter"
+// @Failure 404 {object} Problem "Person or version not found"
+// @Failure 412 {object} Problem "Person was changed since it was read"
+// @Failure 428 {object} Problem "If-Match header missing"
+// @Failure 500 {object} Problem "Failed to revert person"
+// @Router /people/{id}/revert [post]
+func (h *Handlers) RevertPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := personID(r)
+	if err != nil {
+		writeError(w, r, err, "Invalid ID parameter")
+		return
+	}
+
+	toVersion, err := strconv.Atoi(r.URL.Query().Get("to_version"))
+	if err != nil || toVersion <= 0 {
+		writeError(w, r, &paramError{param: "to_version", message: "must be a positive integer"}, "Invalid to_version parameter")
+		return
+	}
+
+	versions, err := h.ifMatch(r)
+	if err != nil {
+		writeError(w, r, err, "Failed to revert person")
+		return
+	}
+
+	var person *postgres.Person
+	err = writeIfMatch(versions, func(version int) (err error) {
+		person, err = h.people.Revert(r.Context(), id, version, toVersion)
+		return err
+	})
+	if err != nil {
+		writeError(w, r, err, "Failed to revert person")
+		return
+	}
+
+	setETag(w, person)
+	writeJSON(w, r, http.StatusOK, person)
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"TestRest/external"
+	"TestRest/internal/validation"
+	"TestRest/pkg/logger"
+	"TestRest/pkg/postgres"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importChunkSize is the number of rows enriched and copied into the database at a time.
+const importChunkSize = 1000
+
+// importChunkTimeout replaces the server's read and write timeouts for imports, whose body is read one chunk at a
+// time between enrichment and storage: it bounds the time to handle the next chunk instead of the whole request.
+const importChunkTimeout = time.Minute
+
+// ImportReport lists which lines of an import file were stored and which were rejected.
+type ImportReport struct {
+	Accepted      int              `json:"accepted"`
+	Rejected      int              `json:"rejected"`
+	AcceptedLines []int            `json:"accepted_lines"`
+	RejectedLines []ImportRowError `json:"rejected_lines"`
+}
+
+// ImportProblem is sent when an import is aborted. Report covers the lines read before that; the accepted ones remain
+// stored.
+type ImportProblem struct {
+	Problem
+	Report ImportReport `json:"report"`
+}
+
+// ImportRowError explains why a line was rejected; Errors lists the invalid fields when the line failed validation.
+type ImportRowError struct {
+	Line   int          `json:"line"`
+	Error  string       `json:"error"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// importRow is one parsed line of an import file. Err is set when the line could not be parsed.
+type importRow struct {
+	Line   int
+	Person postgres.Person
+	Err    error
+}
+
+// importReader yields rows until io.EOF. Any other error aborts the import.
+type importReader interface {
+	Next() (importRow, error)
+}
+
+// ImportPeople stores people from a CSV or NDJSON file.
+// @Summary Import people
+// @Description ImportPeople Bulk-insert people from CSV (with a header row) or NDJSON. Columns: name, surname, patronymic and optional age, gender, nationality; missing optional fields are fetched from external APIs.
+// @Tags people
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param format query string false "csv or ndjson, when Content-Type does not tell"
+// @Success 200 {object} ImportReport
+// @Failure 400 {object} ImportProblem "Invalid import file; report lists the lines read before it"
+// @Failure 409 {object} ImportProblem "A row conflicts with existing data; report lists the lines read before it"
+// @Failure 415 {object} Problem "Unsupported import format"
+// @Failure 422 {object} ImportProblem "A value was rejected by the database; report lists the lines read before it"
+// @Failure 500 {object} ImportProblem "Failed to import people; report lists the lines read before it"
+// @Router /people:import [post]
+func (h *Handlers) ImportPeople(w http.ResponseWriter, r *http.Request) {
+	var reader importReader
+	switch importFormat(r) {
+	case "csv":
+		csvReader, err := newCSVImportReader(r.Body)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid import file: "+err.Error())
+			return
+		}
+		reader = csvReader
+	case "ndjson":
+		reader = newNDJSONImportReader(r.Body)
+	default:
+		writeProblem(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Unsupported import format: use text/csv or application/x-ndjson")
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	extendDeadlines := func() {
+		// Writers that cannot set deadlines, e.g. in tests, have no server deadlines to extend either.
+		deadline := time.Now().Add(importChunkTimeout)
+		_ = controller.SetReadDeadline(deadline)
+		_ = controller.SetWriteDeadline(deadline)
+	}
+	extendDeadlines()
+
+	report := ImportReport{AcceptedLines: []int{}, RejectedLines: []ImportRowError{}}
+	reject := func(line int, err error) {
+		rowErr := ImportRowError{Line: line, Error: err.Error()}
+		var validationErrs validation.Errors
+		if errors.As(err, &validationErrs) {
+			rowErr.Errors = fieldErrors(validationErrs)
+		}
+		report.Rejected++
+		report.RejectedLines = append(report.RejectedLines, rowErr)
+	}
+
+	for done := false; !done; {
+		var chunk []importRow
+		for len(chunk) < importChunkSize {
+			row, err := reader.Next()
+			if errors.Is(err, io.EOF) {
+				done = true
+				break
+			}
+			if err != nil {
+				problem := newProblem(r, http.StatusBadRequest, CodeInvalidBody, "Invalid import file: "+err.Error())
+				sendProblem(w, problem.Status, ImportProblem{Problem: problem, Report: report})
+				return
+			}
+			if row.Err == nil {
+				validation.Normalize(&row.Person)
+				row.Err = validation.Person(row.Person)
+			}
+			if row.Err != nil {
+				reject(row.Line, row.Err)
+				continue
+			}
+			chunk = append(chunk, row)
+		}
+		if len(chunk) == 0 {
+			continue
+		}
+
+		chunk = h.enrichImportRows(r, chunk, reject)
+
+		people := make([]postgres.Person, len(chunk))
+		for i, row := range chunk {
+			people[i] = row.Person
+		}
+		if _, err := h.people.CreateMany(r.Context(), people); err != nil {
+			logger.GetLoggerFromContext(r.Context()).Error(r.Context(), "Import aborted", zap.Int("accepted", report.Accepted), zap.Error(err))
+			problem := errorProblem(r, err, "Failed to import people")
+			sendProblem(w, problem.Status, ImportProblem{Problem: problem, Report: report})
+			return
+		}
+		for _, row := range chunk {
+			report.Accepted++
+			report.AcceptedLines = append(report.AcceptedLines, row.Line)
+		}
+		extendDeadlines()
+	}
+
+	writeJSON(w, r, http.StatusOK, report)
+}
+
+// enrichImportRows fills the missing age, gender and nationality of rows and returns the rows that could be
+// enriched; the others are passed to reject.
+func (h *Handlers) enrichImportRows(r *http.Request, rows []importRow, reject func(int, error)) []importRow {
+	var lookups []external.Lookup
+	var indexes []int
+	for i, row := range rows {
+		var fields external.Fields
+		if row.Person.Age == nil {
+			fields |= external.FieldAge
+		}
+		if row.Person.Gender == nil {
+			fields |= external.FieldGender
+		}
+		if row.Person.Nationality == nil {
+			fields |= external.FieldNationality
+		}
+		if fields != 0 {
+			lookups = append(lookups, external.Lookup{Name: row.Person.Name, Fields: fields})
+			indexes = append(indexes, i)
+		}
+	}
+
+	results, errs := h.enrichment.EnrichMany(r.Context(), lookups)
+
+	failed := map[int]bool{}
+	for i, idx := range indexes {
+		if errs[i] != nil {
+			failed[idx] = true
+			reject(rows[idx].Line, errs[i])
+			continue
+		}
+		p := &rows[idx].Person
+		if results[i].Age != nil {
+			p.Age = &results[i].Age.Age
+		}
+		if results[i].Gender != nil {
+			p.Gender = &results[i].Gender.Gender
+		}
+		if results[i].Nationality != nil {
+			p.Nationality = &results[i].Nationality.CountryID
+		}
+	}
+
+	kept := rows[:0]
+	for i, row := range rows {
+		if !failed[i] {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+// importFormat picks "csv" or "ndjson" from the format query parameter or the Content-Type header.
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return "csv"
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/json-seq":
+		return "ndjson"
+	}
+	return ""
+}
+
+var importColumns = map[string]bool{
+	"name":        true,
+	"surname":     true,
+	"patronymic":  true,
+	"age":         true,
+	"gender":      true,
+	"nationality": true,
+}
+
+type csvImportReader struct {
+	r       *csv.Reader
+	columns []string
+}
+
+// newCSVImportReader reads the header row, which names the columns of the file.
+func newCSVImportReader(body io.Reader) (*csvImportReader, error) {
+	r := csv.NewReader(body)
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+		if !importColumns[header[i]] {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+	}
+	return &csvImportReader{r: r, columns: header}, nil
+}
+
+func (c *csvImportReader) Next() (importRow, error) {
+	record, err := c.r.Read()
+	if errors.Is(err, io.EOF) {
+		return importRow{}, io.EOF
+	}
+
+	var row importRow
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		row.Line = parseErr.StartLine
+		row.Err = parseErr.Err
+		return row, nil
+	}
+	if err != nil {
+		return row, err
+	}
+	row.Line, _ = c.r.FieldPos(0)
+
+	for i, column := range c.columns {
+		if err := setImportField(&row.Person, column, record[i]); err != nil {
+			row.Err = err
+			break
+		}
+	}
+	return row, nil
+}
+
+func setImportField(p *postgres.Person, column, value string) error {
+	value = strings.TrimSpace(value)
+	switch column {
+	case "name":
+		p.Name = value
+	case "surname":
+		p.Surname = value
+	case "patronymic":
+		p.Patronymic = value
+	case "age":
+		if value == "" {
+			return nil
+		}
+		age, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid age %q", value)
+		}
+		p.Age = &age
+	case "gender":
+		if value != "" {
+			p.Gender = &value
+		}
+	case "nationality":
+		if value != "" {
+			p.Nationality = &value
+		}
+	}
+	return nil
+}
+
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONImportReader(body io.Reader) *ndjsonImportReader {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &ndjsonImportReader{scanner: scanner}
+}
+
+func (n *ndjsonImportReader) Next() (importRow, error) {
+	for n.scanner.Scan() {
+		n.line++
+		line := strings.TrimSpace(n.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var record struct {
+			Name        string  `json:"name"`
+			Surname     string  `json:"surname"`
+			Patronymic  string  `json:"patronymic"`
+			Age         *int    `json:"age"`
+			Gender      *string `json:"gender"`
+			Nationality *string `json:"nationality"`
+		}
+		row := importRow{Line: n.line}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			row.Err = fmt.Errorf("invalid JSON: %w", err)
+			return row, nil
+		}
+		row.Person = postgres.Person{
+			Name:        strings.TrimSpace(record.Name),
+			Surname:     strings.TrimSpace(record.Surname),
+			Patronymic:  strings.TrimSpace(record.Patronymic),
+			Age:         record.Age,
+			Gender:      record.Gender,
+			Nationality: record.Nationality,
+		}
+		return row, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		return importRow{}, err
+	}
+	return importRow{}, io.EOF
+}
@@ -0,0 +1,259 @@
+package handlers
+
+// Legacy verb-named routes (/get, /post, /put, /delete). They read their parameters from a JSON body and are kept
+// only for clients that have not yet migrated to the /people collection.
+
+import (
+	"TestRest/internal/validation"
+	"TestRest/pkg/postgres"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// LegacyGetInfo retrieves a person's information by ID.
+// @Summary Get person info (legacy)
+// @Description LegacyGetInfo Get a person's details by their ID. Deprecated: use GET /people.
+// @Tags legacy
+// @Accept json
+// @Produce json
+// @Param id query int true "Person ID"
+// @Success 200 {object} postgres.Person
+// @Failure 400 {object} Problem "Invalid ID parameter"
+// @Failure 500 {object} Problem "Failed to get person"
+// @Deprecated
+// @Router /get [get]
+func (h *Handlers) LegacyGetInfo(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		ID          int    `json:"id"`
+		Name        string `json:"name"`
+		Surname     string `json:"surname"`
+		Patronymic  string `json:"patronymic"`
+		Age         int    `json:"age"`
+		Gender      string `json:"gender"`
+		Nationality string `json:"nationality"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	person, err := h.legacyFind(r.Context(), params.ID, postgres.ListFilter{
+		Name:          params.Name,
+		Surname:       params.Surname,
+		Patronymic:    params.Patronymic,
+		Exact:         true,
+		Age:           optionalInt(params.Age),
+		Genders:       optionalString(params.Gender),
+		Nationalities: optionalString(params.Nationality),
+	})
+	if err != nil {
+		writeError(w, r, err, "Failed to get person")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, person)
+}
+
+// LegacyDeletePerson deletes a person by ID.
+// @Summary Delete person (legacy)
+// @Description LegacyDeletePerson Delete a person by their ID. Deprecated: use DELETE /people/{id}.
+// @Tags legacy
+// @Param id query int true "Person ID"
+// @Param If-Match header string false "ETag of the person being deleted, or a list of ETags any of which may match; when missing, any version is deleted"
+// @Success 200 {string} string "Deleted person by ID"
+// @Failure 400 {object} Problem "Invalid ID parameter"
+// @Failure 412 {object} Problem "Person was changed since it was read"
+// @Failure 500 {object} Problem "Failed to delete person"
+// @Deprecated
+// @Router /delete [delete]
+func (h *Handlers) LegacyDeletePerson(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	versions, err := legacyIfMatch(r)
+	if err != nil {
+		writeError(w, r, err, "Failed to delete person")
+		return
+	}
+
+	err = writeIfMatch(versions, func(version int) error {
+		return h.people.Delete(r.Context(), params.ID, version)
+	})
+	if err != nil {
+		writeError(w, r, err, "Failed to delete person")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Deleted person by id: " + strconv.Itoa(params.ID)))
+}
+
+// LegacyInsertPerson inserts a new person into the database.
+// @Summary Insert person (legacy)
+// @Description LegacyInsertPerson Add a new person to the database. Deprecated: use POST /people.
+// @Tags legacy
+// @Param name query string true "Person's name"
+// @Param surname query string true "Person's surname"
+// @Param patronymic query string false "Person's patronymic"
+// @Success 200 {object} postgres.Person
+// @Failure 422 {object} Problem "Invalid person"
+// @Failure 500 {object} Problem "Failed to insert person"
+// @Deprecated
+// @Router /post [post]
+func (h *Handlers) LegacyInsertPerson(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Name       string `json:"name"`
+		Surname    string `json:"surname"`
+		Patronymic string `json:"patronymic"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	p := postgres.Person{Name: params.Name, Surname: params.Surname, Patronymic: params.Patronymic}
+	validation.Normalize(&p)
+	if err := validation.Person(p); err != nil {
+		writeError(w, r, err, "Invalid person")
+		return
+	}
+
+	enrichment, err := h.enrichment.Enrich(r.Context(), p.Name)
+	if err != nil {
+		writeError(w, r, err, "Failed to insert person")
+		return
+	}
+
+	person, err := h.people.Create(r.Context(), enrichedPerson(p.Name, p.Surname, p.Patronymic, enrichment))
+	if err != nil {
+		writeError(w, r, err, "Failed to insert person")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, person)
+}
+
+// LegacyUpdatePerson updates an existing person's information.
+// @Summary Update person (legacy)
+// @Description LegacyUpdatePerson Update a person's details by their ID. Deprecated: use PUT or PATCH /people/{id}.
+// @Tags legacy
+// @Param id query int true "Person ID"
+// @Param name query string true "Person's name"
+// @Param surname query string true "Person's surname"
+// @Param patronymic query string false "Person's patronymic"
+// @Param If-Match header string false "ETag of the person being updated, or a list of ETags any of which may match; when missing, any version is updated"
+// @Success 200 {object} postgres.Person
+// @Header 200 {string} ETag "New version of the person"
+// @Failure 400 {object} Problem "Invalid ID parameter"
+// @Failure 412 {object} Problem "Person was changed since it was read"
+// @Failure 422 {object} Problem "Invalid person"
+// @Failure 500 {object} Problem "Failed to update person"
+// @Deprecated
+// @Router /put [put]
+func (h *Handlers) LegacyUpdatePerson(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		ID          int    `json:"id"`
+		Name        string `json:"name"`
+		Surname     string `json:"surname"`
+		Patronymic  string `json:"patronymic"`
+		Age         int    `json:"age"`
+		Gender      string `json:"gender"`
+		Nationality string `json:"nationality"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid request body: "+err.Error())
+		return
+	}
+
+	versions, err := legacyIfMatch(r)
+	if err != nil {
+		writeError(w, r, err, "Failed to update person")
+		return
+	}
+
+	// Empty fields have always meant "keep the current value" on this route.
+	var patch postgres.PersonPatch
+	for _, f := range []struct {
+		value string
+		dst   *postgres.PatchField[string]
+	}{
+		{params.Name, &patch.Name},
+		{params.Surname, &patch.Surname},
+		{params.Patronymic, &patch.Patronymic},
+		{params.Gender, &patch.Gender},
+		{params.Nationality, &patch.Nationality},
+	} {
+		if f.value != "" {
+			value := f.value
+			*f.dst = postgres.PatchField[string]{Set: true, Value: &value}
+		}
+	}
+	if params.Age != 0 {
+		patch.Age = postgres.PatchField[int]{Set: true, Value: &params.Age}
+	}
+	validation.NormalizePatch(&patch)
+	if err := validation.Patch(patch); err != nil {
+		writeError(w, r, err, "Invalid person")
+		return
+	}
+
+	var updatedPerson *postgres.Person
+	err = writeIfMatch(versions, func(version int) (err error) {
+		updatedPerson, err = h.people.Patch(r.Context(), params.ID, version, patch)
+		return err
+	})
+	if errors.Is(err, postgres.ErrNotFound) {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid ID or multiple persons found", FieldError{Field: "id", Code: "not_found", Message: "no person with this ID"})
+		return
+	}
+	if err != nil {
+		writeError(w, r, err, "Failed to update person")
+		return
+	}
+
+	setETag(w, updatedPerson)
+	writeJSON(w, r, http.StatusOK, updatedPerson)
+}
+
+// legacyFind reproduces the old /get lookup: a non-zero id selects that single person, otherwise every person whose
+// fields equal the given non-empty values is returned.
+func (h *Handlers) legacyFind(ctx context.Context, id int, filter postgres.ListFilter) ([]postgres.Person, error) {
+	if id != 0 {
+		person, err := h.people.Get(ctx, id)
+		if errors.Is(err, postgres.ErrNotFound) {
+			return []postgres.Person{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []postgres.Person{*person}, nil
+	}
+
+	page, err := h.people.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+func optionalInt(v int) *int {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+func optionalString(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return []string{v}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"TestRest/external"
+	"TestRest/internal/validation"
+	"TestRest/pkg/logger"
+	"TestRest/pkg/postgres"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+	"net/http"
+	"runtime/debug"
+)
+
+// Error codes are part of the API: clients branch on them, so they must not change.
+const (
+	CodeInvalidBody          = "invalid_body"
+	CodeInvalidParameter     = "invalid_parameter"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePreconditionRequired = "precondition_required"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeEnrichmentFailed     = "enrichment_failed"
+	CodeInternal             = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details response body.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes what is wrong with one field of the request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// writeProblem sends an application/problem+json response.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string, fields ...FieldError) {
+	sendProblem(w, status, newProblem(r, status, code, detail, fields...))
+}
+
+// newProblem builds the problem for a response to r.
+func newProblem(r *http.Request, status int, code, detail string, fields ...FieldError) Problem {
+	return Problem{
+		Type:      "/problems/" + code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: middleware.GetReqID(r.Context()),
+		Errors:    fields,
+	}
+}
+
+// sendProblem sends body, a Problem or a struct embedding one to add extension members, as application/problem+json.
+func sendProblem(w http.ResponseWriter, status int, body interface{}) {
+	response, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// fieldErrors converts validation errors to their response form.
+func fieldErrors(errs validation.Errors) []FieldError {
+	fields := make([]FieldError, len(errs))
+	for i, e := range errs {
+		fields[i] = FieldError{Field: e.Field, Code: e.Code, Message: e.Message}
+	}
+	return fields
+}
+
+// writeError sends the problem matching err. Errors without a problem of their own are logged, since the response
+// only carries the generic detail.
+func writeError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	problem := errorProblem(r, err, detail)
+	if problem.Code == CodeInternal {
+		logger.GetLoggerFromContext(r.Context()).Error(r.Context(), detail, zap.Error(err))
+	}
+	sendProblem(w, problem.Status, problem)
+}
+
+// errorProblem returns the problem matching err: a known error gets its own status and code, anything else is a 500
+// with the given detail.
+func errorProblem(r *http.Request, err error, detail string) Problem {
+	var providerErr *external.ProviderError
+	var paramErr *paramError
+	var validationErrs validation.Errors
+	switch {
+	case errors.As(err, &paramErr):
+		return newProblem(r, http.StatusBadRequest, CodeInvalidParameter, paramErr.Error(), FieldError{Field: paramErr.param, Code: "invalid", Message: paramErr.message})
+	case errors.As(err, &validationErrs):
+		return newProblem(r, http.StatusUnprocessableEntity, CodeValidationFailed, detail+": some fields are invalid", fieldErrors(validationErrs)...)
+	case errors.Is(err, postgres.ErrNotFound):
+		return newProblem(r, http.StatusNotFound, CodeNotFound, "Person not found")
+	case errors.Is(err, postgres.ErrVersionNotFound):
+		return newProblem(r, http.StatusNotFound, CodeNotFound, "Person version not found")
+	case errors.Is(err, postgres.ErrVersionMismatch):
+		return newProblem(r, http.StatusPreconditionFailed, CodePreconditionFailed, detail+": the person was changed since it was read; fetch it again for the current ETag")
+	case errors.Is(err, errPreconditionRequired):
+		return newProblem(r, http.StatusPreconditionRequired, CodePreconditionRequired, detail+": send the ETag of the person in If-Match")
+	case errors.Is(err, postgres.ErrConflict):
+		return newProblem(r, http.StatusConflict, CodeConflict, detail+": the change conflicts with existing data")
+	case errors.Is(err, postgres.ErrValidation):
+		return newProblem(r, http.StatusUnprocessableEntity, CodeValidationFailed, detail+": a value was rejected by the database")
+	case errors.Is(err, postgres.ErrInvalidCursor):
+		return newProblem(r, http.StatusBadRequest, CodeInvalidParameter, "Invalid cursor parameter", FieldError{Field: "cursor", Code: "invalid", Message: err.Error()})
+	case errors.As(err, &providerErr):
+		return newProblem(r, http.StatusBadGateway, CodeEnrichmentFailed, detail+" - unable to fetch "+providerErr.Provider)
+	default:
+		return newProblem(r, http.StatusInternalServerError, CodeInternal, detail)
+	}
+}
+
+// paramError reports an invalid query or path parameter.
+type paramError struct {
+	param   string
+	message string
+}
+
+func (e *paramError) Error() string {
+	if e.message == "" {
+		return "Invalid " + e.param + " parameter"
+	}
+	return "Invalid " + e.param + " parameter: " + e.message
+}
+
+// NotFound answers requests for unknown routes.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusNotFound, CodeNotFound, "No route for "+r.URL.Path)
+}
+
+// MethodNotAllowed answers requests with a method the route does not support.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not supported for "+r.URL.Path)
+}
+
+// Recoverer answers a request whose handler panicked with an internal error problem and logs the panic with its stack
+// trace. http.ErrAbortHandler is re-panicked, so that the server still aborts the response silently.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			logger.GetLoggerFromContext(r.Context()).Error(r.Context(), "Handler panicked", zap.Any("panic", rec), zap.ByteString("stack", debug.Stack()))
+			writeProblem(w, r, http.StatusInternalServerError, CodeInternal, "Internal server error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
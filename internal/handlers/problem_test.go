@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"TestRest/pkg/logger/loggertest"
+	"TestRest/pkg/postgres"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestRecoverer(t *testing.T) {
+	handler := Recoverer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("nil map")
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/people/1", nil))
+
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("body %q: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/problem+json" ||
+		problem.Code != CodeInternal || problem.Instance != "/people/1" {
+		t.Errorf("got %d %s %+v, want an internal_error problem", w.Code, w.Header().Get("Content-Type"), problem)
+	}
+
+	t.Run("abort", func(t *testing.T) {
+		defer func() {
+			if rec := recover(); rec != http.ErrAbortHandler {
+				t.Errorf("recovered %v, want http.ErrAbortHandler passed on", rec)
+			}
+		}()
+		Recoverer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic(http.ErrAbortHandler)
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestWriteErrorLogsInternalErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantLogged bool
+	}{
+		{name: "unknown error", err: errors.New("conn closed"), wantStatus: http.StatusInternalServerError, wantLogged: true},
+		{name: "known error", err: postgres.ErrNotFound, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, logs := loggertest.NewObserved(context.Background())
+			r := httptest.NewRequest(http.MethodGet, "/people/1", nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			writeError(w, r, tt.err, "Failed to get person")
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			entries := logs.FilterMessage("Failed to get person").All()
+			if logged := len(entries) == 1 && entries[0].ContextMap()["error"] == tt.err.Error(); logged != tt.wantLogged || len(entries) > 1 {
+				t.Errorf("logged %v, want logged %t", entries, tt.wantLogged)
+			}
+		})
+	}
+}
+
+func TestImportPeopleAborted(t *testing.T) {
+	// The second line is rejected before the body fails to read.
+	body := io.MultiReader(strings.NewReader("name,surname\nIvan,\n"), iotest.ErrReader(errors.New("connection reset")))
+	r := httptest.NewRequest(http.MethodPost, "/people:import", body)
+	r.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	(&Handlers{}).ImportPeople(w, r)
+
+	var problem ImportProblem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("body %q: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" || problem.Code != CodeInvalidBody {
+		t.Errorf("got %d %s %+v, want an invalid_body problem", w.Code, w.Header().Get("Content-Type"), problem.Problem)
+	}
+	if !strings.Contains(problem.Detail, "connection reset") {
+		t.Errorf("detail = %q, want the read error", problem.Detail)
+	}
+	if problem.Report.Rejected != 1 || len(problem.Report.RejectedLines) != 1 || problem.Report.RejectedLines[0].Line != 2 {
+		t.Errorf("report = %+v, want line 2 rejected", problem.Report)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"TestRest/pkg/postgres"
+	"net/http"
+)
+
+// ListTrash returns a page of deleted people that have not been purged yet.
+// @Summary List deleted people
+// @Description ListTrash Get deleted people that can still be restored, with the same filters and pagination as GET /people
+// @Tags people
+// @Produce json
+// @Param name query string false "Name prefix (case-insensitive)"
+// @Param surname query string false "Surname prefix (case-insensitive)"
+// @Param patronymic query string false "Patronymic prefix (case-insensitive)"
+// @Param sort query string false "Sort fields, '-' prefix for descending, e.g. -age,surname"
+// @Param limit query int false "Page size" default(50)
+// @Param offset query int false "Rows to skip"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} postgres.PersonPage
+// @Failure 400 {object} Problem "Invalid query parameter"
+// @Failure 500 {object} Problem "Failed to get deleted people"
+// @Router /people/trash [get]
+func (h *Handlers) ListTrash(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseListFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err, "Invalid query parameter")
+		return
+	}
+	filter.Trashed = true
+
+	page, err := h.people.List(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, err, "Failed to get deleted people")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, page)
+}
+
+// RestorePerson takes a deleted person out of the trash.
+// @Summary Restore person
+// @Description RestorePerson Undo the deletion of a person that has not been purged yet
+// @Tags people
+// @Produce json
+// @Param id path int true "Person ID"
+// @Param If-Match header string false "ETag of the deleted person, or a list of ETags any of which may match; required unless REQUIRE_IF_MATCH=false"
+// @Success 200 {object} postgres.Person
+// @Header 200 {string} ETag "New version of the person"
+// @Failure 400 {object} Problem "Invalid ID parameter"
+// @Failure 404 {object} Problem "Person not found in the trash"
+// @Failure 412 {object} Problem "Person was changed since it was read"
+// @Failure 428 {object} Problem "If-Match header missing"
+// @Failure 500 {object} Problem "Failed to restore person"
+// @Router /people/{id}/restore [post]
+func (h *Handlers) RestorePerson(w http.ResponseWriter, r *http.Request) {
+	id, err := personID(r)
+	if err != nil {
+		writeError(w, r, err, "Invalid ID parameter")
+		return
+	}
+
+	versions, err := h.ifMatch(r)
+	if err != nil {
+		writeError(w, r, err, "Failed to restore person")
+		return
+	}
+
+	var person *postgres.Person
+	err = writeIfMatch(versions, func(version int) (err error) {
+		person, err = h.people.Restore(r.Context(), id, version)
+		return err
+	})
+	if err != nil {
+		writeError(w, r, err, "Failed to restore person")
+		return
+	}
+
+	setETag(w, person)
+	writeJSON(w, r, http.StatusOK, person)
+}
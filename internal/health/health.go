@@ -0,0 +1,171 @@
+// Package health reports whether the service is alive and able to take traffic.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Config struct {
+	// CacheTTL is how long a readiness result is reused, so that frequent probes do not each hit the dependencies.
+	CacheTTL time.Duration `yaml:"CACHE_TTL" env:"HEALTH_CACHE_TTL" env-default:"2s"`
+	// Timeout bounds each dependency check.
+	Timeout time.Duration `yaml:"TIMEOUT" env:"HEALTH_TIMEOUT" env-default:"2s"`
+	// CheckEnrichment adds the reachability of the enrichment provider to the readiness report.
+	CheckEnrichment bool `yaml:"CHECK_ENRICHMENT" env:"HEALTH_CHECK_ENRICHMENT" env-default:"false"`
+}
+
+// Readiness is set once the server accepts requests and cleared when shutdown starts, so that load balancers stop
+// routing new requests while in-flight ones drain.
+type Readiness struct {
+	ready atomic.Bool
+}
+
+func (r *Readiness) Set(ready bool) {
+	r.ready.Store(ready)
+}
+
+func (r *Readiness) Ready() bool {
+	return r.ready.Load()
+}
+
+// Check is one dependency of the service. An Optional check is reported but does not make the service unready.
+type Check struct {
+	Name     string
+	Optional bool
+	Run      func(ctx context.Context) error
+}
+
+// Status is the body of a health response.
+type Status struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of one Check.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	Optional  bool    `json:"optional,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Checker runs the dependency checks for readiness probes and caches their result.
+type Checker struct {
+	cfg       Config
+	readiness *Readiness
+	checks    []Check
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	status    Status
+	ready     bool
+}
+
+func NewChecker(cfg Config, readiness *Readiness, checks ...Check) *Checker {
+	return &Checker{cfg: cfg, readiness: readiness, checks: checks}
+}
+
+// Check runs every check concurrently, or returns the previous result if it is younger than Config.CacheTTL.
+func (c *Checker) Check(ctx context.Context) (Status, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.cfg.CacheTTL {
+		return c.status, c.ready
+	}
+
+	results := make([]CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, check, c.cfg.Timeout)
+		}()
+	}
+	wg.Wait()
+
+	status := Status{Status: "ready", Checks: make(map[string]CheckResult, len(c.checks))}
+	ready := true
+	for i, check := range c.checks {
+		status.Checks[check.Name] = results[i]
+		if results[i].Error != "" && !check.Optional {
+			status.Status = "not ready"
+			ready = false
+		}
+	}
+
+	c.checkedAt, c.status, c.ready = time.Now(), status, ready
+	return status, ready
+}
+
+// run runs check detached from the probe's cancellation, since its result is cached for other probes.
+func run(ctx context.Context, check Check, timeout time.Duration) CheckResult {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	result := CheckResult{
+		Status:    "ok",
+		Optional:  check.Optional,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Healthz answers 200 as long as the process can serve HTTP.
+// @Summary Liveness probe
+// @Description Healthz Report that the process is alive
+// @Tags health
+// @Produce json
+// @Success 200 {object} Status
+// @Router /healthz [get]
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, Status{Status: "ok"})
+}
+
+// Readyz answers 200 with a breakdown per dependency when the service can take traffic, and 503 while it is
+// shutting down or a required dependency is failing.
+// @Summary Readiness probe
+// @Description Readyz Check Postgres, the schema version and optionally the enrichment provider; results are cached briefly
+// @Tags health
+// @Produce json
+// @Success 200 {object} Status
+// @Failure 503 {object} Status "Not ready"
+// @Router /readyz [get]
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	if !c.readiness.Ready() {
+		writeStatus(w, http.StatusServiceUnavailable, Status{Status: "shutting down"})
+		return
+	}
+
+	status, ready := c.Check(r.Context())
+	if !ready {
+		writeStatus(w, http.StatusServiceUnavailable, status)
+		return
+	}
+	writeStatus(w, http.StatusOK, status)
+}
+
+func writeStatus(w http.ResponseWriter, status int, v interface{}) {
+	response, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(response)
+}
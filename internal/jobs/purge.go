@@ -0,0 +1,44 @@
+// Package jobs contains background work that runs alongside the HTTP server.
+package jobs
+
+import (
+	"TestRest/pkg/logger"
+	"TestRest/pkg/postgres"
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Purger permanently removes people that have been in the trash for longer than the retention period.
+type Purger struct {
+	people    postgres.PeopleRepository
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewPurger(people postgres.PeopleRepository, retention, interval time.Duration) *Purger {
+	return &Purger{people: people, retention: retention, interval: interval}
+}
+
+// purgeActor is the actor recorded in the audit log for purged people.
+const purgeActor = "trash-purge"
+
+// Run purges once immediately and then every interval until ctx is done. Failures are logged and retried on the
+// next tick.
+func (p *Purger) Run(ctx context.Context) {
+	ctx = postgres.WithAuditInfo(ctx, postgres.AuditInfo{Actor: purgeActor})
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.people.Purge(ctx, time.Now().Add(-p.retention)); err != nil {
+			logger.GetLoggerFromContext(ctx).Error(ctx, "Failed to purge deleted persons", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package metrics exposes Prometheus metrics of the HTTP server, the database pool and the schema.
+package metrics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by method, route pattern and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// SchemaVersion is the migration version the database was brought to at startup.
+	SchemaVersion = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "schema_migration_version",
+		Help: "Version of the last applied database migration.",
+	})
+)
+
+// Handler serves the metrics of the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records the duration of every request under its chi route pattern, e.g. /people/{id}, so that
+// metrics do not grow with the number of distinct URLs.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		requestDuration.WithLabelValues(r.Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// poolCollector reports pgxpool statistics at scrape time.
+type poolCollector struct {
+	db *pgxpool.Pool
+
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	maxConns        *prometheus.Desc
+	acquireCount    *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquire    *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+}
+
+// NewPoolCollector returns a collector of the connection statistics of db.
+func NewPoolCollector(db *pgxpool.Pool) prometheus.Collector {
+	return &poolCollector{
+		db:              db,
+		acquiredConns:   prometheus.NewDesc("pgxpool_acquired_conns", "Connections currently in use.", nil, nil),
+		idleConns:       prometheus.NewDesc("pgxpool_idle_conns", "Connections currently idle.", nil, nil),
+		totalConns:      prometheus.NewDesc("pgxpool_total_conns", "Connections currently open.", nil, nil),
+		maxConns:        prometheus.NewDesc("pgxpool_max_conns", "Maximum size of the pool.", nil, nil),
+		acquireCount:    prometheus.NewDesc("pgxpool_acquire_total", "Connections acquired from the pool.", nil, nil),
+		acquireDuration: prometheus.NewDesc("pgxpool_acquire_wait_seconds_total", "Time spent waiting to acquire a connection.", nil, nil),
+		emptyAcquire:    prometheus.NewDesc("pgxpool_empty_acquire_total", "Acquisitions that had to wait because no connection was idle.", nil, nil),
+		canceledAcquire: prometheus.NewDesc("pgxpool_canceled_acquire_total", "Acquisitions cancelled by their context.", nil, nil),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.db.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
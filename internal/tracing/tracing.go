@@ -0,0 +1,97 @@
+// Package tracing exports OpenTelemetry traces of the service over OTLP and propagates W3C trace context.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// TracerName is the instrumentation scope of the spans started by this package.
+const TracerName = "TestRest/internal/tracing"
+
+type Config struct {
+	// Enabled turns on the export of spans. When disabled, spans are not recorded but incoming trace context is
+	// still propagated to the enrichment providers.
+	Enabled bool `yaml:"ENABLED" env:"TRACING_ENABLED" env-default:"false"`
+	// Endpoint is the host:port of the OTLP/HTTP collector.
+	Endpoint string `yaml:"ENDPOINT" env:"TRACING_ENDPOINT" env-default:"localhost:4318"`
+	// Insecure sends spans over plain HTTP instead of HTTPS.
+	Insecure bool `yaml:"INSECURE" env:"TRACING_INSECURE" env-default:"true"`
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string `yaml:"SERVICE_NAME" env:"TRACING_SERVICE_NAME" env-default:"testrest"`
+	// SampleRatio is the fraction of new traces that are sampled. Traces started by a caller follow its decision.
+	SampleRatio float64 `yaml:"SAMPLE_RATIO" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+}
+
+// Setup installs the global propagator and, if tracing is enabled, a tracer provider exporting to cfg.Endpoint.
+// The returned function flushes and stops the exporter; call it before the process exits.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Middleware starts a server span for every request, continuing the trace of the caller's traceparent header. The
+// span is named after the route pattern once the router has matched it, so that its name does not depend on IDs.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := otel.Tracer(TracerName).Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		if id := middleware.GetReqID(ctx); id != "" {
+			span.SetAttributes(attribute.String("http.request_id", id))
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
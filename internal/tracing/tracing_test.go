@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"TestRest/internal/tracing/tracingtest"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	exporter, _ := tracingtest.NewProvider()
+
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/people/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, child := otel.Tracer("test").Start(r.Context(), "child")
+		child.End()
+		if chi.URLParam(r, "id") == "0" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	tests := []struct {
+		name        string
+		path        string
+		traceparent string
+		wantStatus  int
+		wantCode    codes.Code
+	}{
+		{name: "new trace", path: "/people/7", wantStatus: http.StatusOK, wantCode: codes.Unset},
+		{name: "caller's trace", path: "/people/7", traceparent: "00-" + traceID + "-" + spanID + "-01", wantStatus: http.StatusOK, wantCode: codes.Unset},
+		{name: "server error", path: "/people/0", wantStatus: http.StatusInternalServerError, wantCode: codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.traceparent != "" {
+				r.Header.Set("traceparent", tt.traceparent)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := exporter.GetSpans().Snapshots()
+			if len(spans) != 2 {
+				t.Fatalf("recorded %d spans, want the handler's and the server's", len(spans))
+			}
+			child, server := spans[0], spans[1]
+
+			if server.Name() != "GET /people/{id}" || server.SpanKind() != trace.SpanKindServer {
+				t.Errorf("server span %q of kind %s, want GET /people/{id} of kind server", server.Name(), server.SpanKind())
+			}
+			if got := attributeValue(server, "http.response.status_code"); got.AsInt64() != int64(tt.wantStatus) {
+				t.Errorf("status attribute = %v, want %d", got.Emit(), tt.wantStatus)
+			}
+			if got := attributeValue(server, "http.route"); got.AsString() != "/people/{id}" {
+				t.Errorf("route attribute = %q", got.Emit())
+			}
+			if server.Status().Code != tt.wantCode {
+				t.Errorf("span status = %s, want %s", server.Status().Code, tt.wantCode)
+			}
+			if child.Parent().SpanID() != server.SpanContext().SpanID() {
+				t.Errorf("handler span is not a child of the server span")
+			}
+
+			if tt.traceparent == "" {
+				if server.Parent().IsValid() {
+					t.Errorf("server span has parent %s without a traceparent", server.Parent().SpanID())
+				}
+				return
+			}
+			if server.SpanContext().TraceID().String() != traceID || server.Parent().SpanID().String() != spanID || !server.Parent().IsRemote() {
+				t.Errorf("server span %s/%s does not continue the caller's trace", server.SpanContext().TraceID(), server.Parent().SpanID())
+			}
+		})
+	}
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
@@ -0,0 +1,200 @@
+// Package validation checks person data before it reaches the database. It collects every problem instead of
+// stopping at the first one, so clients can fix all fields at once.
+package validation
+
+import (
+	"TestRest/pkg/postgres"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxNameLength matches the VARCHAR(100) columns of the "people" table.
+	MaxNameLength = 100
+	MinAge        = 0
+	MaxAge        = 150
+)
+
+// Codes of FieldError.
+const (
+	CodeRequired          = "required"
+	CodeTooLong           = "too_long"
+	CodeInvalidCharacters = "invalid_characters"
+	CodeOutOfRange        = "out_of_range"
+	CodeInvalidEnum       = "invalid_enum"
+	CodeInvalidCountry    = "invalid_country"
+)
+
+// FieldError describes what is wrong with one field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors is the set of problems found in one payload.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, f := range e {
+		parts[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Validator accumulates field errors.
+type Validator struct {
+	errs Errors
+}
+
+func (v *Validator) add(field, code, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns the collected Errors, or nil if there are none.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Name checks a name, surname or patronymic: at most MaxNameLength characters, made of Unicode letters separated
+// by single hyphens, spaces or apostrophes.
+func (v *Validator) Name(field, value string, required bool) {
+	if value == "" {
+		if required {
+			v.add(field, CodeRequired, "is required")
+		}
+		return
+	}
+	if n := utf8.RuneCountInString(value); n > MaxNameLength {
+		v.add(field, CodeTooLong, "must be at most %d characters, got %d", MaxNameLength, n)
+		return
+	}
+	if !validName(value) {
+		v.add(field, CodeInvalidCharacters, "must consist of letters separated by single hyphens, spaces or apostrophes")
+	}
+}
+
+func validName(s string) bool {
+	prevSeparator := true // a name may not start with a separator
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r):
+			prevSeparator = false
+		case unicode.Is(unicode.Mn, r):
+			// Combining marks must follow a letter.
+			if prevSeparator {
+				return false
+			}
+		case r == '-' || r == ' ' || r == '\'' || r == '’':
+			if prevSeparator {
+				return false
+			}
+			prevSeparator = true
+		default:
+			return false
+		}
+	}
+	return !prevSeparator
+}
+
+// Age checks that a non-nil age lies within MinAge and MaxAge.
+func (v *Validator) Age(field string, value *int) {
+	if value != nil && (*value < MinAge || *value > MaxAge) {
+		v.add(field, CodeOutOfRange, "must be between %d and %d", MinAge, MaxAge)
+	}
+}
+
+// Gender checks that a non-nil gender is "m" or "f".
+func (v *Validator) Gender(field string, value *string) {
+	if value != nil && *value != "m" && *value != "f" {
+		v.add(field, CodeInvalidEnum, `must be "m" or "f"`)
+	}
+}
+
+// Nationality checks that a non-nil nationality is an ISO 3166-1 alpha-2 country code.
+func (v *Validator) Nationality(field string, value *string) {
+	if value != nil && !countries[*value] {
+		v.add(field, CodeInvalidCountry, "must be an ISO 3166-1 alpha-2 country code, e.g. RU")
+	}
+}
+
+// Normalize trims the names of p, lower-cases its gender and upper-cases its nationality.
+func Normalize(p *postgres.Person) {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Surname = strings.TrimSpace(p.Surname)
+	p.Patronymic = strings.TrimSpace(p.Patronymic)
+	if p.Gender != nil {
+		gender := strings.ToLower(strings.TrimSpace(*p.Gender))
+		p.Gender = &gender
+	}
+	if p.Nationality != nil {
+		nationality := strings.ToUpper(strings.TrimSpace(*p.Nationality))
+		p.Nationality = &nationality
+	}
+}
+
+// Person checks every field of p; name and surname are required. Call Normalize first.
+func Person(p postgres.Person) error {
+	var v Validator
+	v.Name("name", p.Name, true)
+	v.Name("surname", p.Surname, true)
+	v.Name("patronymic", p.Patronymic, false)
+	v.Age("age", p.Age)
+	v.Gender("gender", p.Gender)
+	v.Nationality("nationality", p.Nationality)
+	return v.Err()
+}
+
+// NormalizePatch applies Normalize to the fields set in patch.
+func NormalizePatch(patch *postgres.PersonPatch) {
+	for _, f := range []*postgres.PatchField[string]{&patch.Name, &patch.Surname, &patch.Patronymic} {
+		if f.Value != nil {
+			value := strings.TrimSpace(*f.Value)
+			f.Value = &value
+		}
+	}
+	if patch.Gender.Value != nil {
+		gender := strings.ToLower(strings.TrimSpace(*patch.Gender.Value))
+		patch.Gender.Value = &gender
+	}
+	if patch.Nationality.Value != nil {
+		nationality := strings.ToUpper(strings.TrimSpace(*patch.Nationality.Value))
+		patch.Nationality.Value = &nationality
+	}
+}
+
+// Patch checks the fields set in patch. Name and surname may be changed but not cleared. Call NormalizePatch first.
+func Patch(patch postgres.PersonPatch) error {
+	var v Validator
+	for _, f := range []struct {
+		field string
+		value postgres.PatchField[string]
+	}{
+		{"name", patch.Name},
+		{"surname", patch.Surname},
+	} {
+		if f.value.Set {
+			v.Name(f.field, stringValue(f.value.Value), true)
+		}
+	}
+	if patch.Patronymic.Set {
+		v.Name("patronymic", stringValue(patch.Patronymic.Value), false)
+	}
+	v.Age("age", patch.Age.Value)
+	v.Gender("gender", patch.Gender.Value)
+	v.Nationality("nationality", patch.Nationality.Value)
+	return v.Err()
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,159 @@
+package validation
+
+import (
+	"TestRest/pkg/postgres"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "Ivan", want: true},
+		{in: "Jean-Luc", want: true},
+		{in: "Mary Ann", want: true},
+		{in: "O'Brien", want: true},
+		{in: "D’Artagnan", want: true},
+		{in: "Ёлкин", want: true},
+		{in: "José", want: true},
+		{in: "Jose\u0301", want: true}, // combining acute accent after a letter
+		{in: "\u0301Jose", want: false},
+		{in: "-Ivan", want: false},
+		{in: "Ivan-", want: false},
+		{in: "Ivan ", want: false},
+		{in: "Jean--Luc", want: false},
+		{in: "Mary  Ann", want: false},
+		{in: "Jean- Luc", want: false},
+		{in: "-", want: false},
+		{in: "Ivan2", want: false},
+		{in: "Ivan_Petrov", want: false},
+		{in: "Ivan\tPetrov", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := validName(tt.in); got != tt.want {
+				t.Errorf("validName(%q) = %t, want %t", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidator(t *testing.T) {
+	intp := func(n int) *int { return &n }
+	strp := func(s string) *string { return &s }
+
+	tests := []struct {
+		name     string
+		check    func(v *Validator)
+		wantCode string // empty when the value is valid
+	}{
+		{name: "required name missing", check: func(v *Validator) { v.Name("name", "", true) }, wantCode: CodeRequired},
+		{name: "optional name missing", check: func(v *Validator) { v.Name("patronymic", "", false) }},
+		{name: "name at max length", check: func(v *Validator) { v.Name("name", strings.Repeat("я", MaxNameLength), true) }},
+		{name: "name too long", check: func(v *Validator) { v.Name("name", strings.Repeat("я", MaxNameLength+1), true) }, wantCode: CodeTooLong},
+		{name: "name with digits", check: func(v *Validator) { v.Name("name", "R2D2", true) }, wantCode: CodeInvalidCharacters},
+		{name: "age missing", check: func(v *Validator) { v.Age("age", nil) }},
+		{name: "age min", check: func(v *Validator) { v.Age("age", intp(MinAge)) }},
+		{name: "age max", check: func(v *Validator) { v.Age("age", intp(MaxAge)) }},
+		{name: "age negative", check: func(v *Validator) { v.Age("age", intp(-1)) }, wantCode: CodeOutOfRange},
+		{name: "age above max", check: func(v *Validator) { v.Age("age", intp(MaxAge+1)) }, wantCode: CodeOutOfRange},
+		{name: "gender m", check: func(v *Validator) { v.Gender("gender", strp("m")) }},
+		{name: "gender f", check: func(v *Validator) { v.Gender("gender", strp("f")) }},
+		{name: "gender missing", check: func(v *Validator) { v.Gender("gender", nil) }},
+		{name: "gender upper case", check: func(v *Validator) { v.Gender("gender", strp("M")) }, wantCode: CodeInvalidEnum},
+		{name: "gender word", check: func(v *Validator) { v.Gender("gender", strp("male")) }, wantCode: CodeInvalidEnum},
+		{name: "country", check: func(v *Validator) { v.Nationality("nationality", strp("RU")) }},
+		{name: "country missing", check: func(v *Validator) { v.Nationality("nationality", nil) }},
+		{name: "country lower case", check: func(v *Validator) { v.Nationality("nationality", strp("ru")) }, wantCode: CodeInvalidCountry},
+		{name: "country alpha-3", check: func(v *Validator) { v.Nationality("nationality", strp("RUS")) }, wantCode: CodeInvalidCountry},
+		{name: "unassigned country", check: func(v *Validator) { v.Nationality("nationality", strp("XX")) }, wantCode: CodeInvalidCountry},
+		{name: "empty country", check: func(v *Validator) { v.Nationality("nationality", strp("")) }, wantCode: CodeInvalidCountry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v Validator
+			tt.check(&v)
+			err := v.Err()
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var errs Errors
+			if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Code != tt.wantCode {
+				t.Errorf("error = %v, want one %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestPerson(t *testing.T) {
+	gender, nationality, age := " M ", " ru ", 200
+	p := postgres.Person{Name: " Ivan ", Surname: "", Patronymic: "  ", Age: &age, Gender: &gender, Nationality: &nationality}
+
+	Normalize(&p)
+	if p.Name != "Ivan" || p.Patronymic != "" || *p.Gender != "m" || *p.Nationality != "RU" {
+		t.Fatalf("normalized to %+v", p)
+	}
+
+	// Every problem is reported at once.
+	var errs Errors
+	if !errors.As(Person(p), &errs) {
+		t.Fatalf("Person(%+v) = %v, want Errors", p, errs)
+	}
+	got := map[string]string{}
+	for _, e := range errs {
+		got[e.Field] = e.Code
+	}
+	if want := map[string]string{"surname": CodeRequired, "age": CodeOutOfRange}; !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %v, want %v", got, want)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	set := func(s string) postgres.PatchField[string] { return postgres.PatchField[string]{Set: true, Value: &s} }
+	null := postgres.PatchField[string]{Set: true}
+
+	tests := []struct {
+		name       string
+		patch      postgres.PersonPatch
+		wantFields []string
+	}{
+		{name: "empty", patch: postgres.PersonPatch{}},
+		{name: "rename", patch: postgres.PersonPatch{Name: set(" Petr ")}},
+		{name: "clear name", patch: postgres.PersonPatch{Name: null}, wantFields: []string{"name"}},
+		{name: "blank surname", patch: postgres.PersonPatch{Surname: set("  ")}, wantFields: []string{"surname"}},
+		{name: "clear patronymic", patch: postgres.PersonPatch{Patronymic: null}},
+		{name: "clear gender and nationality", patch: postgres.PersonPatch{Gender: null, Nationality: null}},
+		{name: "normalized values", patch: postgres.PersonPatch{Gender: set("F"), Nationality: set("kz")}},
+		{
+			name:       "invalid values",
+			patch:      postgres.PersonPatch{Patronymic: set("-"), Gender: set("x"), Nationality: set("ZZ")},
+			wantFields: []string{"patronymic", "gender", "nationality"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			NormalizePatch(&tt.patch)
+			err := Patch(tt.patch)
+
+			var fields []string
+			var errs Errors
+			if errors.As(err, &errs) {
+				for _, e := range errs {
+					fields = append(fields, e.Field)
+				}
+			} else if err != nil {
+				t.Fatalf("Patch error = %v, want Errors", err)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("invalid fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
@@ -3,93 +3,236 @@ package logger
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"fmt"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
-const (
-	LoggerKey = "logger"
-)
+// contextKey is the key of the Logger in a context. It is unexported so that only this package can set it.
+type contextKey struct{}
+
+// fallback is returned by GetLoggerFromContext for contexts without a Logger: the last one created by New, or a
+// no-op logger before that.
+var fallback atomic.Pointer[Logger]
+
+func init() {
+	fallback.Store(&Logger{l: zap.NewNop(), level: zap.NewAtomicLevel(), redactor: newRedactor(defaultRedaction)})
+}
+
+// Config selects the level, format and destinations of the logs.
+type Config struct {
+	// Level is the minimum level logged: debug, info, warn or error. It can be changed at runtime through
+	// LevelHandler.
+	Level string `yaml:"LEVEL" env:"LOG_LEVEL" env-default:"info"`
+	// Encoding is "json" for machine-parseable lines or "console" for human-readable ones.
+	Encoding string `yaml:"ENCODING" env:"LOG_ENCODING" env-default:"json"`
+	// OutputPaths are the files or "stdout"/"stderr" the logs are written to; ErrorOutputPaths receive the errors of
+	// the logger itself.
+	OutputPaths      []string `yaml:"OUTPUT_PATHS" env:"LOG_OUTPUT_PATHS" env-default:"stderr"`
+	ErrorOutputPaths []string `yaml:"ERROR_OUTPUT_PATHS" env:"LOG_ERROR_OUTPUT_PATHS" env-default:"stderr"`
+	// Within each second, the first SamplingInitial entries with the same level and message are logged and then only
+	// every SamplingThereafter-th. A SamplingInitial of 0 logs every entry.
+	SamplingInitial    int `yaml:"SAMPLING_INITIAL" env:"LOG_SAMPLING_INITIAL" env-default:"100"`
+	SamplingThereafter int `yaml:"SAMPLING_THEREAFTER" env:"LOG_SAMPLING_THEREAFTER" env-default:"100"`
+	// LevelEndpoint registers the runtime level endpoint on the API router. It has no authentication, so only enable it
+	// where the API cannot be reached from outside the deployment.
+	LevelEndpoint bool `yaml:"LEVEL_ENDPOINT" env:"LOG_LEVEL_ENDPOINT" env-default:"false"`
+
+	// Redaction applies to the bodies and query strings logged by Middleware.
+	Redaction RedactionConfig `yaml:"REDACTION"`
+}
 
 type Logger struct {
-	l *zap.Logger
+	l        *zap.Logger
+	level    zap.AtomicLevel
+	redactor *redactor
 }
 
-func New(ctx context.Context) (context.Context, error) {
-	logger, err := zap.NewDevelopment()
+func New(ctx context.Context, cfg Config) (context.Context, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Redaction.PIIMode {
+	case RedactHash, RedactMask, RedactDrop:
+	default:
+		return nil, fmt.Errorf("unknown PII redaction mode %q", cfg.Redaction.PIIMode)
+	}
+
+	zapConfig := zap.NewProductionConfig()
+	if cfg.Encoding == "console" {
+		zapConfig.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+	zapConfig.Level = level
+	zapConfig.Encoding = cfg.Encoding
+	zapConfig.OutputPaths = cfg.OutputPaths
+	zapConfig.ErrorOutputPaths = cfg.ErrorOutputPaths
+	zapConfig.Sampling = nil
+	if cfg.SamplingInitial > 0 {
+		zapConfig.Sampling = &zap.SamplingConfig{Initial: cfg.SamplingInitial, Thereafter: cfg.SamplingThereafter}
+	}
+
+	// Skip the methods of Logger so that entries point at their callers.
+	logger, err := zapConfig.Build(zap.AddCallerSkip(1))
 	if err != nil {
 		return nil, err
 	}
 
-	ctx = context.WithValue(ctx, LoggerKey, &Logger{logger})
+	l := &Logger{l: logger, level: level, redactor: newRedactor(cfg.Redaction)}
+	fallback.Store(l)
 
-	return ctx, nil
+	return context.WithValue(ctx, contextKey{}, l), nil
 }
 
+// NewFromZap returns a copy of ctx with a Logger writing to l, whose level is level, and redacting with the default
+// policy. Unlike New, it does not replace the fallback logger. It lets tests, e.g. through loggertest, log to a zap
+// core of their own.
+func NewFromZap(ctx context.Context, l *zap.Logger, level zap.AtomicLevel) context.Context {
+	return context.WithValue(ctx, contextKey{}, &Logger{l: l, level: level, redactor: newRedactor(defaultRedaction)})
+}
+
+// GetLoggerFromContext returns the Logger of ctx, or the fallback logger if ctx has none.
 func GetLoggerFromContext(ctx context.Context) *Logger {
-	return ctx.Value(LoggerKey).(*Logger)
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return fallback.Load()
 }
 
+// With returns a copy of ctx whose Logger adds fields to every entry, e.g. the ID of the person a request is about.
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	l := GetLoggerFromContext(ctx)
+	return context.WithValue(ctx, contextKey{}, &Logger{l: l.l.With(fields...), level: l.level, redactor: l.redactor})
+}
+
+func (l *Logger) Debug(ctx context.Context, msg string, fields ...zap.Field) {
+	l.l.Debug(msg, contextFields(ctx, fields)...)
+}
 func (l *Logger) Info(ctx context.Context, msg string, fields ...zap.Field) {
-	if middleware.GetReqID(ctx) != "" {
-		fields = append(fields, zap.String("RequestID", middleware.GetReqID(ctx)))
-	}
-	l.l.Info(msg, fields...)
+	l.l.Info(msg, contextFields(ctx, fields)...)
+}
+func (l *Logger) Warn(ctx context.Context, msg string, fields ...zap.Field) {
+	l.l.Warn(msg, contextFields(ctx, fields)...)
+}
+func (l *Logger) Error(ctx context.Context, msg string, fields ...zap.Field) {
+	l.l.Error(msg, contextFields(ctx, fields)...)
 }
 func (l *Logger) Fatal(ctx context.Context, msg string, fields ...zap.Field) {
+	l.l.Fatal(msg, contextFields(ctx, fields)...)
+}
+
+// LevelHandler reports the current level on GET and changes it on PUT with a body like {"level":"debug"}.
+func (l *Logger) LevelHandler() http.Handler {
+	return l.level
+}
+
+// contextFields appends the request ID and the trace and span IDs of ctx to fields, so that log lines can be
+// correlated with requests and traces.
+func contextFields(ctx context.Context, fields []zap.Field) []zap.Field {
 	if middleware.GetReqID(ctx) != "" {
 		fields = append(fields, zap.String("RequestID", middleware.GetReqID(ctx)))
 	}
-	l.l.Fatal(msg, fields...)
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		fields = append(fields, zap.String("trace_id", span.TraceID().String()), zap.String("span_id", span.SpanID().String()))
+	}
+	return fields
 }
 
-type responseRecorder struct {
-	http.ResponseWriter
-	statusCode int
-	body       *bytes.Buffer
+// Sync flushes buffered log entries. Call it before the process exits.
+func (l *Logger) Sync() error {
+	return l.l.Sync()
 }
 
-func (rr *responseRecorder) WriteHeader(code int) {
-	rr.statusCode = code
-	rr.ResponseWriter.WriteHeader(code)
+// prefixWriter keeps the first limit bytes written to it and drops the rest, so that a response can be logged
+// without holding all of it. Nothing is kept unless the response is JSON, which is known by the time of the first
+// write.
+type prefixWriter struct {
+	header http.Header
+	limit  int
+	buf    bytes.Buffer
+	skip   bool
+	seen   bool
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	if !p.seen {
+		p.seen = true
+		p.skip = !isJSON(p.header.Get("Content-Type"))
+	}
+	if !p.skip && p.buf.Len() < p.limit {
+		p.buf.Write(b[:min(len(b), p.limit-p.buf.Len())])
+	}
+	return len(b), nil
 }
 
-func (rr *responseRecorder) Write(b []byte) (int, error) {
-	rr.body.Write(b)
-	return rr.ResponseWriter.Write(b)
+// isJSON reports whether contentType is JSON, including types such as application/problem+json.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
 }
 
+// Middleware logs every request and its response with the number of bytes written and the duration. Bodies are
+// only logged for paths the redaction policy allows, when they are JSON, and with its redactions applied; the query
+// string is redacted the same way. At most the body size limit plus one byte of either body is held in memory, and
+// the ResponseWriter keeps its optional interfaces such as http.Flusher, so streaming responses are unaffected.
 func Middleware(baseCtx context.Context) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
 			logger := GetLoggerFromContext(baseCtx)
-
-			var bodyBytes []byte
-			if r.Body != nil {
-				bodyBytes, _ = io.ReadAll(r.Body)
-			}
-
-			r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-			var params map[string]interface{}
-			if err := json.Unmarshal(bodyBytes, &params); err != nil {
-				params = map[string]interface{}{"error": "failed to parse body"}
+			logBody := logger.redactor.logsBody(r.URL.Path)
+
+			fields := []zap.Field{zap.String("method", r.Method), zap.String("url", logger.redactor.url(r.URL)), zap.Int64("content_length", r.ContentLength)}
+			if logBody && r.Body != nil && r.Body != http.NoBody && isJSON(r.Header.Get("Content-Type")) {
+				// One byte more than the limit tells a body at the limit from a larger one.
+				prefix, err := io.ReadAll(io.LimitReader(r.Body, int64(logger.redactor.maxBody)+1))
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(prefix), errReader{err}, r.Body), r.Body}
+				fields = append(fields, zap.Any("params", logger.redactor.body(prefix)))
 			}
+			logger.Info(r.Context(), "Incoming request", fields...)
 
-			if logger != nil {
-				logger.Info(r.Context(), "Incoming request", zap.String("method", r.Method), zap.String("url", r.URL.String()), zap.Any("params", params))
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			var response *prefixWriter
+			if logBody {
+				response = &prefixWriter{header: ww.Header(), limit: logger.redactor.maxBody + 1}
+				ww.Tee(response)
 			}
 
-			recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK, body: &bytes.Buffer{}}
-
-			ctx := context.WithValue(r.Context(), LoggerKey, logger)
-			next.ServeHTTP(recorder, r.WithContext(ctx))
+			ctx := context.WithValue(r.Context(), contextKey{}, logger)
+			next.ServeHTTP(ww, r.WithContext(ctx))
 
-			if logger != nil {
-				logger.Info(ctx, "Response sent", zap.Int("status", recorder.statusCode), zap.String("response", recorder.body.String()))
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			fields = []zap.Field{zap.Int("status", status), zap.Int("bytes", ww.BytesWritten()), zap.Duration("duration", time.Since(start))}
+			if response != nil && response.seen && !response.skip {
+				fields = append(fields, zap.Any("response", logger.redactor.body(response.buf.Bytes())))
 			}
+			logger.Info(ctx, "Response sent", fields...)
 		})
 	}
 }
+
+// errReader fails every read with err, or reports the end of input if err is nil. It passes an error hit while
+// reading the logged prefix of a request body on to the handler.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return 0, io.EOF
+}
@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFallback(t *testing.T) {
+	previous := fallback.Load()
+	t.Cleanup(func() { fallback.Store(previous) })
+
+	observedCtx, logs := newObserved(context.Background())
+	fallback.Store(GetLoggerFromContext(observedCtx))
+
+	// Contexts without a Logger, including those derived with With, log through the fallback.
+	ctx := context.Background()
+	GetLoggerFromContext(ctx).Info(ctx, "plain")
+	withCtx := With(ctx, zap.Int("person_id", 7))
+	GetLoggerFromContext(withCtx).Info(withCtx, "with")
+
+	entries := logs.TakeAll()
+	if len(entries) != 2 || entries[0].Message != "plain" || entries[1].Message != "with" {
+		t.Fatalf("entries = %v, want plain and with", entries)
+	}
+	if len(entries[0].Context) != 0 {
+		t.Errorf("plain entry has fields %v", entries[0].ContextMap())
+	}
+	if got := entries[1].ContextMap()["person_id"]; got != int64(7) {
+		t.Errorf("person_id = %v, want 7", got)
+	}
+}
+
+func TestWith(t *testing.T) {
+	ctx, logs := newObserved(context.Background())
+	outer := With(ctx, zap.Int("person_id", 7))
+	inner := With(outer, zap.String("step", "enrich"))
+
+	GetLoggerFromContext(ctx).Info(ctx, "base")
+	GetLoggerFromContext(outer).Info(outer, "outer")
+	GetLoggerFromContext(inner).Info(inner, "inner")
+
+	want := []map[string]interface{}{
+		{},
+		{"person_id": int64(7)},
+		{"person_id": int64(7), "step": "enrich"},
+	}
+	entries := logs.TakeAll()
+	if len(entries) != len(want) {
+		t.Fatalf("logged %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if got := entry.ContextMap(); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("%s: fields = %v, want %v", entry.Message, got, want[i])
+		}
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	ctx, logs := newObserved(context.Background())
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, "host/req-000001")
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+
+	GetLoggerFromContext(ctx).Warn(ctx, "correlated", zap.String("kind", "age"))
+
+	want := map[string]interface{}{
+		"kind":      "age",
+		"RequestID": "host/req-000001",
+		"trace_id":  "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":   "00f067aa0ba902b7",
+	}
+	if got := logs.TakeAll()[0].ContextMap(); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	large := `{"name":"` + strings.Repeat("a", defaultRedaction.MaxBodyBytes) + `"}`
+
+	tests := []struct {
+		name         string
+		target       string
+		body         string
+		responseType string
+		response     string
+		wantURL      string
+		wantParams   interface{} // nil when not logged
+		wantResponse interface{} // nil when not logged
+	}{
+		{
+			name:         "JSON bodies",
+			target:       "/people",
+			body:         `{"age":30,"password":"secret"}`,
+			responseType: "application/json",
+			response:     `{"id":1}`,
+			wantURL:      "/people",
+			wantParams:   map[string]interface{}{"age": 30.0, "password": redacted},
+			wantResponse: map[string]interface{}{"id": 1.0},
+		},
+		{
+			name:         "problem response",
+			target:       "/people/1",
+			responseType: "application/problem+json",
+			response:     `{"status":404}`,
+			wantURL:      "/people/1",
+			wantResponse: map[string]interface{}{"status": 404.0},
+		},
+		{
+			name:         "redacted query",
+			target:       "/people?cursor=abc&limit=10",
+			responseType: "application/json",
+			response:     `[]`,
+			wantURL:      "/people?cursor=%5Bredacted%5D&limit=10",
+			wantResponse: []interface{}{},
+		},
+		{
+			name:         "body over the limit",
+			target:       "/people",
+			body:         large,
+			responseType: "application/json",
+			response:     large,
+			wantURL:      "/people",
+			wantParams:   "[omitted: larger than the logging limit]",
+			wantResponse: "[omitted: larger than the logging limit]",
+		},
+		{
+			name:         "skipped path",
+			target:       "/people:import",
+			body:         `{"age":30}`,
+			responseType: "application/json",
+			response:     `{"imported":1}`,
+			wantURL:      "/people:import",
+		},
+		{
+			name:         "text response",
+			target:       "/metrics",
+			responseType: "text/plain",
+			response:     "up 1",
+			wantURL:      "/metrics",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, logs := newObserved(context.Background())
+			var received string
+			handler := Middleware(ctx)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = string(body)
+				w.Header().Set("Content-Type", tt.responseType)
+				_, _ = io.WriteString(w, tt.response)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			// Logging never changes what the handler reads or the client receives.
+			if received != tt.body || w.Body.String() != tt.response {
+				t.Errorf("handler read %d bytes of %d, client received %d of %d", len(received), len(tt.body), w.Body.Len(), len(tt.response))
+			}
+
+			entries := logs.TakeAll()
+			if len(entries) != 2 {
+				t.Fatalf("logged %d entries, want request and response", len(entries))
+			}
+			request, response := entries[0].ContextMap(), entries[1].ContextMap()
+			if request["url"] != tt.wantURL {
+				t.Errorf("url = %v, want %s", request["url"], tt.wantURL)
+			}
+			if !reflect.DeepEqual(request["params"], tt.wantParams) {
+				t.Errorf("params = %#v, want %#v", request["params"], tt.wantParams)
+			}
+			if !reflect.DeepEqual(response["response"], tt.wantResponse) {
+				t.Errorf("response = %#v, want %#v", response["response"], tt.wantResponse)
+			}
+			if response["status"] != int64(http.StatusOK) || response["bytes"] != int64(len(tt.response)) {
+				t.Errorf("status %v, bytes %v", response["status"], response["bytes"])
+			}
+		})
+	}
+}
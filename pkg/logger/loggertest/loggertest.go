@@ -0,0 +1,19 @@
+// Package loggertest captures what the service logs for tests.
+package loggertest
+
+import (
+	"TestRest/pkg/logger"
+	"context"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// NewObserved returns a copy of ctx with a Logger that keeps every entry, from debug level up, in the returned
+// ObservedLogs instead of writing it, so that tests can assert on what was logged. Fatal panics instead of exiting.
+// Bodies logged by logger.Middleware are redacted with the default policy.
+func NewObserved(ctx context.Context) (context.Context, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(core, zap.WithFatalHook(zapcore.WriteThenPanic))
+	return logger.NewFromZap(ctx, l, zap.NewAtomicLevelAt(zapcore.DebugLevel)), logs
+}
@@ -0,0 +1,15 @@
+package logger
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newObserved is loggertest.NewObserved, which the tests of this package cannot import without a cycle.
+func newObserved(ctx context.Context) (context.Context, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := zap.New(core, zap.WithFatalHook(zapcore.WriteThenPanic))
+	return NewFromZap(ctx, l, zap.NewAtomicLevelAt(zapcore.DebugLevel)), logs
+}
@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// Redaction modes for PII fields.
+const (
+	RedactHash = "hash"
+	RedactMask = "mask"
+	RedactDrop = "drop"
+)
+
+// redacted replaces values that must not be logged.
+const redacted = "[redacted]"
+
+// RedactionConfig decides what of the request and response bodies and query strings ends up in the logs. Field names
+// are matched case-insensitively at any depth of a JSON body.
+type RedactionConfig struct {
+	// PIIFields are replaced according to PIIMode: "hash" logs a keyed hash, so that entries about the same value can
+	// still be correlated, "mask" keeps only the first letter and "drop" replaces the value entirely.
+	PIIFields []string `yaml:"PII_FIELDS" env:"LOG_PII_FIELDS" env-default:"name,surname,patronymic"`
+	PIIMode   string   `yaml:"PII_MODE" env:"LOG_PII_MODE" env-default:"hash"`
+	// PIIHashKey keys the "hash" mode, so that hashes of common names cannot be computed by anyone reading the logs.
+	// When empty, a random key is generated at startup: hashes then only correlate entries of the same process.
+	PIIHashKey string `yaml:"PII_HASH_KEY" env:"LOG_PII_HASH_KEY" secret:"true"`
+	// DenyFields are always replaced. List cursors are denied by default since they encode the sort key of a row,
+	// e.g. a surname. When AllowFields is not empty, only the values of the fields it lists are
+	// logged; objects and arrays are still descended into.
+	DenyFields  []string `yaml:"DENY_FIELDS" env:"LOG_DENY_FIELDS" env-default:"password,token,secret,cursor,next_cursor"`
+	AllowFields []string `yaml:"ALLOW_FIELDS" env:"LOG_ALLOW_FIELDS"`
+	// MaxBodyBytes is the size above which a body is not logged; 0 never logs bodies.
+	MaxBodyBytes int `yaml:"MAX_BODY_BYTES" env:"LOG_MAX_BODY_BYTES" env-default:"4096"`
+	// SkipBodyPaths are the paths whose bodies are never logged. A trailing "*" matches any path with that prefix.
+	SkipBodyPaths []string `yaml:"SKIP_BODY_PATHS" env:"LOG_SKIP_BODY_PATHS" env-default:"/healthz,/readyz,/metrics,/swagger/*,/people:export,/people:import"`
+}
+
+// defaultRedaction is the policy of loggers not created by New. It matches the env-default tags of RedactionConfig.
+var defaultRedaction = RedactionConfig{
+	PIIFields:     []string{"name", "surname", "patronymic"},
+	PIIMode:       RedactHash,
+	DenyFields:    []string{"password", "token", "secret", "cursor", "next_cursor"},
+	MaxBodyBytes:  4096,
+	SkipBodyPaths: []string{"/healthz", "/readyz", "/metrics", "/swagger/*", "/people:export", "/people:import"},
+}
+
+// redactor applies a RedactionConfig.
+type redactor struct {
+	pii       map[string]bool
+	deny      map[string]bool
+	allow     map[string]bool
+	mode      string
+	hashKey   []byte
+	maxBody   int
+	skipPaths []string
+}
+
+func newRedactor(cfg RedactionConfig) *redactor {
+	hashKey := []byte(cfg.PIIHashKey)
+	if len(hashKey) == 0 {
+		hashKey = make([]byte, sha256.Size)
+		// crypto/rand.Read never fails on supported platforms.
+		_, _ = rand.Read(hashKey)
+	}
+	return &redactor{
+		pii:       fieldSet(cfg.PIIFields),
+		deny:      fieldSet(cfg.DenyFields),
+		allow:     fieldSet(cfg.AllowFields),
+		mode:      cfg.PIIMode,
+		hashKey:   hashKey,
+		maxBody:   cfg.MaxBodyBytes,
+		skipPaths: cfg.SkipBodyPaths,
+	}
+}
+
+func fieldSet(fields []string) map[string]bool {
+	set := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			set[strings.ToLower(field)] = true
+		}
+	}
+	return set
+}
+
+// logsBody reports whether bodies of requests to path may be logged.
+func (r *redactor) logsBody(path string) bool {
+	if r.maxBody <= 0 {
+		return false
+	}
+	for _, skip := range r.skipPaths {
+		if prefix, ok := strings.CutSuffix(skip, "*"); ok && strings.HasPrefix(path, prefix) || skip == path {
+			return false
+		}
+	}
+	return true
+}
+
+// body returns the redacted form of a JSON body, or a short description of why it is not logged.
+func (r *redactor) body(body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	if len(body) > r.maxBody {
+		return "[omitted: larger than the logging limit]"
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "[omitted: not JSON]"
+	}
+	return r.value("", v)
+}
+
+// value redacts v, the value of the field key.
+func (r *redactor) value(key string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			v[k] = r.value(k, field)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.value(key, item)
+		}
+		return v
+	}
+
+	key = strings.ToLower(key)
+	switch {
+	case r.deny[key]:
+		return redacted
+	case r.pii[key]:
+		return r.pseudonymize(v)
+	case len(r.allow) > 0 && key != "" && !r.allow[key]:
+		return redacted
+	}
+	return v
+}
+
+// url returns the URL of a request with the values of its query parameters redacted like body fields.
+func (r *redactor) url(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	query := u.Query()
+	for key, values := range query {
+		for i, value := range values {
+			if s, ok := r.value(strings.TrimSuffix(key, "[]"), value).(string); ok {
+				values[i] = s
+			}
+		}
+	}
+	redactedURL := *u
+	redactedURL.RawQuery = query.Encode()
+	return redactedURL.String()
+}
+
+func (r *redactor) pseudonymize(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return v
+	}
+	switch r.mode {
+	case RedactHash:
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(s))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil))[:16]
+	case RedactMask:
+		first, size := utf8.DecodeRuneInString(s)
+		return string(first) + strings.Repeat("*", utf8.RuneCountInString(s[size:]))
+	default:
+		return redacted
+	}
+}
@@ -0,0 +1,151 @@
+package logger
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactorValue(t *testing.T) {
+	r := newRedactor(RedactionConfig{
+		PIIFields:  []string{"name", " Surname "},
+		PIIMode:    RedactMask,
+		DenyFields: []string{"password", "cursor"},
+	})
+	allowing := newRedactor(RedactionConfig{
+		PIIFields:   []string{"name"},
+		PIIMode:     RedactDrop,
+		DenyFields:  []string{"password"},
+		AllowFields: []string{"id", "password", "name", "people"},
+	})
+
+	tests := []struct {
+		name string
+		r    *redactor
+		in   interface{}
+		want interface{}
+	}{
+		{name: "plain value", r: r, in: map[string]interface{}{"age": 30.0}, want: map[string]interface{}{"age": 30.0}},
+		{name: "denied", r: r, in: map[string]interface{}{"password": "x", "Cursor": "abc"}, want: map[string]interface{}{"password": redacted, "Cursor": redacted}},
+		{name: "masked", r: r, in: map[string]interface{}{"NAME": "Ivan", "surname": "Ёлкин"}, want: map[string]interface{}{"NAME": "I***", "surname": "Ё****"}},
+		{name: "empty and non-string PII", r: r, in: map[string]interface{}{"name": "", "surname": nil}, want: map[string]interface{}{"name": "", "surname": nil}},
+		{
+			name: "nested and arrays",
+			r:    r,
+			in:   map[string]interface{}{"people": []interface{}{map[string]interface{}{"name": "Ivan"}}, "name": []interface{}{"Ann", "Bob"}},
+			want: map[string]interface{}{"people": []interface{}{map[string]interface{}{"name": "I***"}}, "name": []interface{}{"A**", "B**"}},
+		},
+		{name: "top-level scalar", r: r, in: "Ivan", want: "Ivan"},
+		{
+			name: "allow list",
+			r:    allowing,
+			in:   map[string]interface{}{"id": 1.0, "age": 30.0, "password": "x", "name": "Ivan", "people": []interface{}{map[string]interface{}{"id": 2.0}}},
+			want: map[string]interface{}{"id": 1.0, "age": redacted, "password": redacted, "name": redacted, "people": []interface{}{map[string]interface{}{"id": 2.0}}},
+		},
+		{name: "allow list top-level scalar", r: allowing, in: 7.0, want: 7.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.value("", tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("value = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorHash(t *testing.T) {
+	hash := func(key, s string) interface{} {
+		return newRedactor(RedactionConfig{PIIFields: []string{"name"}, PIIMode: RedactHash, PIIHashKey: key}).value("name", s)
+	}
+
+	got := hash("k1", "Ivan")
+	if s, _ := got.(string); !strings.HasPrefix(s, "hmac:") || len(s) != len("hmac:")+16 || strings.Contains(s, "Ivan") {
+		t.Fatalf("hash = %v, want hmac: and 16 hex digits", got)
+	}
+	if again := hash("k1", "Ivan"); again != got {
+		t.Errorf("same key and value hashed to %v and %v", got, again)
+	}
+	if other := hash("k1", "Petr"); other == got {
+		t.Errorf("different values hashed to %v", got)
+	}
+	if other := hash("k2", "Ivan"); other == got {
+		t.Errorf("different keys hashed to %v", got)
+	}
+	// Without a configured key, every redactor gets its own random one.
+	if a, b := hash("", "Ivan"), hash("", "Ivan"); a == b {
+		t.Errorf("hashes without a key are predictable: %v", a)
+	}
+}
+
+func TestRedactorURL(t *testing.T) {
+	r := newRedactor(RedactionConfig{
+		PIIFields:  []string{"name", "surname"},
+		PIIMode:    RedactDrop,
+		DenyFields: []string{"cursor"},
+	})
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "/people", want: "/people"},
+		{in: "/people?limit=10", want: "/people?limit=10"},
+		{in: "/people?name=Ivan&limit=10", want: "/people?limit=10&name=%5Bredacted%5D"},
+		{in: "/people?name[]=Ivan&name[]=Petr", want: "/people?name%5B%5D=%5Bredacted%5D&name%5B%5D=%5Bredacted%5D"},
+		{in: "/people?cursor=eyJzIjoic3VybmFtZSJ9&SURNAME=Petrov", want: "/people?SURNAME=%5Bredacted%5D&cursor=%5Bredacted%5D"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			u, err := url.Parse(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.url(u); got != tt.want {
+				t.Errorf("url(%s) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorBody(t *testing.T) {
+	r := newRedactor(RedactionConfig{
+		PIIFields:     []string{"name"},
+		PIIMode:       RedactDrop,
+		MaxBodyBytes:  32,
+		SkipBodyPaths: []string{"/healthz", "/swagger/*"},
+	})
+
+	for path, want := range map[string]bool{
+		"/people":             true,
+		"/healthz":            false,
+		"/healthz/extra":      true,
+		"/swagger/index.html": false,
+		"/swagger":            true,
+	} {
+		if got := r.logsBody(path); got != want {
+			t.Errorf("logsBody(%s) = %t, want %t", path, got, want)
+		}
+	}
+	if newRedactor(RedactionConfig{}).logsBody("/people") {
+		t.Error("bodies are logged with MaxBodyBytes 0")
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want interface{}
+	}{
+		{name: "empty", in: "", want: nil},
+		{name: "redacted", in: `{"name":"Ivan","age":30}`, want: map[string]interface{}{"name": redacted, "age": 30.0}},
+		{name: "too large", in: `{"name":"` + strings.Repeat("a", 32) + `"}`, want: "[omitted: larger than the logging limit]"},
+		{name: "not JSON", in: "name=Ivan", want: "[omitted: not JSON]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.body([]byte(tt.in)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("body(%s) = %#v, want %#v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+UPDATE people
+SET age = COALESCE(age, 0),
+    nationality = COALESCE(nationality, ''),
+    gender = COALESCE(gender, '');
+
+ALTER TABLE people
+    ALTER COLUMN age SET NOT NULL,
+    ALTER COLUMN nationality SET NOT NULL,
+    ALTER COLUMN gender SET NOT NULL;
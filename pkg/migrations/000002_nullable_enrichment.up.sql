@@ -0,0 +1,4 @@
+ALTER TABLE people
+    ALTER COLUMN age DROP NOT NULL,
+    ALTER COLUMN nationality DROP NOT NULL,
+    ALTER COLUMN gender DROP NOT NULL;
@@ -0,0 +1 @@
+DROP TABLE name_enrichment;
@@ -0,0 +1,7 @@
+CREATE TABLE name_enrichment (
+    name TEXT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    value JSONB NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (name, kind)
+);
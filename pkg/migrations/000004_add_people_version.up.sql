@@ -0,0 +1,2 @@
+ALTER TABLE people
+    ADD COLUMN version INT NOT NULL DEFAULT 1;
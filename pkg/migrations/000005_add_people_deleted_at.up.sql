@@ -0,0 +1,4 @@
+ALTER TABLE people
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX people_deleted_at_idx ON people (deleted_at) WHERE deleted_at IS NOT NULL;
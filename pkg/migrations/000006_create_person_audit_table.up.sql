@@ -0,0 +1,12 @@
+CREATE TABLE person_audit (
+    id BIGSERIAL PRIMARY KEY,
+    person_id INT NOT NULL,
+    operation VARCHAR(16) NOT NULL,
+    actor TEXT,
+    request_id TEXT,
+    old_row JSONB,
+    new_row JSONB,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX person_audit_person_id_idx ON person_audit (person_id, id);
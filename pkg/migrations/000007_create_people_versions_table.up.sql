@@ -0,0 +1,21 @@
+CREATE TABLE people_versions (
+    person_id INT NOT NULL,
+    version INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    surname VARCHAR(100) NOT NULL,
+    patronymic VARCHAR(100),
+    age INT,
+    nationality VARCHAR(100),
+    gender CHAR(1),
+    deleted_at TIMESTAMPTZ,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ,
+    PRIMARY KEY (person_id, version)
+);
+
+CREATE INDEX people_versions_valid_idx ON people_versions (person_id, valid_from);
+
+-- Earlier versions of existing people were never recorded; their history starts now.
+INSERT INTO people_versions (person_id, version, name, surname, patronymic, age, nationality, gender, deleted_at, valid_from)
+SELECT id, version, name, surname, patronymic, age, nationality, gender, deleted_at, now()
+FROM people;
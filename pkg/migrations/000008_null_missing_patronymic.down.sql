@@ -0,0 +1,2 @@
+UPDATE people SET patronymic = '' WHERE patronymic IS NULL;
+UPDATE people_versions SET patronymic = '' WHERE patronymic IS NULL;
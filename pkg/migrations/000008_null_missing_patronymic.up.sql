@@ -0,0 +1,2 @@
+UPDATE people SET patronymic = NULL WHERE patronymic = '';
+UPDATE people_versions SET patronymic = NULL WHERE patronymic = '';
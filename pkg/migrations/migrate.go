@@ -2,11 +2,17 @@ package migrations
 
 import (
 	"TestRest/internal/config"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"os"
+	"strconv"
+	"strings"
 )
 
 func RunMigrations(db *pgxpool.Pool, migrationsPath string, config config.Config) error {
@@ -25,3 +31,38 @@ func RunMigrations(db *pgxpool.Pool, migrationsPath string, config config.Config
 
 	return nil
 }
+
+// LatestVersion returns the highest version among the migrations in migrationsPath, which is the version the
+// database is at once RunMigrations succeeded.
+func LatestVersion(migrationsPath string) (uint, error) {
+	files, err := os.ReadDir(migrationsPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	var latest uint
+	for _, f := range files {
+		prefix, _, ok := strings.Cut(f.Name(), "_")
+		if !ok || !strings.HasSuffix(f.Name(), ".up.sql") {
+			continue
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest, nil
+}
+
+// CurrentVersion returns the version recorded by golang-migrate in the database, and whether a migration to it
+// failed halfway.
+func CurrentVersion(ctx context.Context, db *pgxpool.Pool) (version uint, dirty bool, err error) {
+	err = db.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	return version, dirty, nil
+}
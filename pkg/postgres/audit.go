@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"TestRest/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Operations recorded in the person_audit table.
+const (
+	OperationInsert  = "insert"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+	OperationRevert  = "revert"
+	OperationPurge   = "purge"
+)
+
+// AuditInfo says who is making the changes recorded in the person_audit table.
+type AuditInfo struct {
+	Actor     string
+	RequestID string
+}
+
+type auditInfoKey struct{}
+
+// WithAuditInfo returns a context whose writes are attributed to info.
+func WithAuditInfo(ctx context.Context, info AuditInfo) context.Context {
+	return context.WithValue(ctx, auditInfoKey{}, info)
+}
+
+func auditInfo(ctx context.Context) AuditInfo {
+	info, _ := ctx.Value(auditInfoKey{}).(AuditInfo)
+	return info
+}
+
+// AuditEntry is one change to a person. OldRow is null for inserts and NewRow for purges.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	PersonID  int             `json:"person_id"`
+	Operation string          `json:"operation"`
+	Actor     *string         `json:"actor"`
+	RequestID *string         `json:"request_id"`
+	OldRow    json.RawMessage `json:"old_row" swaggertype:"object"`
+	NewRow    json.RawMessage `json:"new_row" swaggertype:"object"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+// History returns the changes to person id, oldest first. People that never existed have no history and yield
+// ErrNotFound; deleted and purged people keep theirs. People unchanged since before auditing began have an empty
+// history.
+func (r *Repository) History(ctx context.Context, id int) ([]AuditEntry, error) {
+	query := `
+		SELECT id, person_id, operation, actor, request_id, old_row, new_row, changed_at
+		FROM person_audit
+		WHERE person_id = $1
+		ORDER BY id
+	`
+	rows, err := r.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve person history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.PersonID, &e.Operation, &e.Actor, &e.RequestID, &e.OldRow, &e.NewRow, &e.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	if len(entries) == 0 {
+		var exists bool
+		if err := r.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM people WHERE id = $1)", id).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check person: %w", err)
+		}
+		if !exists {
+			return nil, ErrNotFound
+		}
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Retrieved person history", zap.Int("id", id), zap.Int("count", len(entries)))
+	return entries, nil
+}
+
+// audited runs write, a change to person id, in a transaction together with its audit entry and new version. The row, live or in the
+// trash as trashed says, is locked first so that the recorded old row is exactly the one write replaces. write
+// returns pgx.ErrNoRows when its version check fails.
+func (r *Repository) audited(ctx context.Context, operation string, id int, trashed bool, write func(pgx.Tx) (*Person, error)) (*Person, error) {
+	var person *Person
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		var old []byte
+		query := "SELECT to_jsonb(p) FROM people p WHERE id = $1 AND (deleted_at IS NOT NULL) = $2 FOR UPDATE"
+		err := tx.QueryRow(ctx, query, id, trashed).Scan(&old)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock person: %w", err)
+		}
+
+		person, err = write(tx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The row exists and is locked, so only the version check can have failed.
+			return ErrVersionMismatch
+		}
+		if err != nil {
+			return err
+		}
+		if err := audit(ctx, tx, operation, id, old); err != nil {
+			return err
+		}
+		return recordVersion(ctx, tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return person, nil
+}
+
+// audit records a change to person id whose new state is the current row; old is the row before it, nil for inserts.
+func audit(ctx context.Context, tx pgx.Tx, operation string, id int, old []byte) error {
+	info := auditInfo(ctx)
+	query := `
+		INSERT INTO person_audit (person_id, operation, actor, request_id, old_row, new_row)
+		SELECT $1, $2, $3, $4, $5::jsonb, to_jsonb(p) FROM people p WHERE p.id = $1
+	`
+	if _, err := tx.Exec(ctx, query, id, operation, nullString(info.Actor), nullString(info.RequestID), old); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+// EnrichmentCache stores enrichment results in the "name_enrichment" table. It implements external.CacheStore.
+type EnrichmentCache struct {
+	db *pgxpool.Pool
+}
+
+func NewEnrichmentCache(db *pgxpool.Pool) *EnrichmentCache {
+	return &EnrichmentCache{db: db}
+}
+
+// Get returns the cached value of the given kind for name, or nil if there is none or it has expired.
+func (c *EnrichmentCache) Get(ctx context.Context, name, kind string) ([]byte, error) {
+	query := `
+		SELECT value
+		FROM name_enrichment
+		WHERE name = $1 AND kind = $2 AND expires_at > now()
+	`
+	var value []byte
+	err := c.db.QueryRow(ctx, query, name, kind).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read enrichment cache: %w", err)
+	}
+	return value, nil
+}
+
+func (c *EnrichmentCache) Set(ctx context.Context, name, kind string, value []byte, ttl time.Duration) error {
+	query := `
+		INSERT INTO name_enrichment (name, kind, value, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name, kind) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at
+	`
+	if _, err := c.db.Exec(ctx, query, name, kind, value, time.Now().Add(ttl)); err != nil {
+		return fmt.Errorf("failed to write enrichment cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrNotFound means the requested person does not exist.
+	ErrNotFound = errors.New("person not found")
+	// ErrConflict means the write clashes with existing data, e.g. a unique constraint.
+	ErrConflict = errors.New("conflict")
+	// ErrVersionNotFound means the requested version of a person was never recorded.
+	ErrVersionNotFound = errors.New("person version not found")
+	// ErrVersionMismatch means a conditional write found the person at a different version than expected.
+	ErrVersionMismatch = errors.New("person version does not match")
+	// ErrValidation means the database rejected a value, e.g. a string longer than its column.
+	ErrValidation = errors.New("invalid value")
+)
+
+// classify wraps err with ErrConflict or ErrValidation when it is a PostgreSQL error of that kind, and adds msg.
+func classify(msg string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505", // unique_violation
+			"23503", // foreign_key_violation
+			"23P01": // exclusion_violation
+			return fmt.Errorf("%s: %w: %w", msg, ErrConflict, err)
+		case "23502", // not_null_violation
+			"23514", // check_violation
+			"22001", // string_data_right_truncation
+			"22003", // numeric_value_out_of_range
+			"22P02": // invalid_text_representation
+			return fmt.Errorf("%s: %w: %w", msg, ErrValidation, err)
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,393 @@
+package postgres
+
+import (
+	"TestRest/pkg/logger"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"math"
+	"strings"
+)
+
+// sortColumns lists the columns people can be sorted by.
+var sortColumns = map[string]bool{
+	"id":          true,
+	"name":        true,
+	"surname":     true,
+	"patronymic":  true,
+	"age":         true,
+	"gender":      true,
+	"nationality": true,
+}
+
+// SortField is a single column of an ORDER BY clause.
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// ParseSort parses a sort expression such as "-age,surname", where a leading "-" means descending order.
+func ParseSort(s string) ([]SortField, error) {
+	var fields []SortField
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Column: strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+"), Desc: strings.HasPrefix(part, "-")}
+		if !sortColumns[field.Column] {
+			return nil, fmt.Errorf("unknown sort field %q", field.Column)
+		}
+		if seen[field.Column] {
+			return nil, fmt.Errorf("duplicate sort field %q", field.Column)
+		}
+		seen[field.Column] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// ListFilter describes which people to list and how to page through them.
+type ListFilter struct {
+	// Name, Surname and Patronymic match as case-insensitive prefixes, or as whole values when Exact is set.
+	Name       string
+	Surname    string
+	Patronymic string
+	Exact      bool
+
+	Age    *int
+	AgeGTE *int
+	AgeLTE *int
+
+	Genders       []string
+	Nationalities []string
+
+	// Trashed lists the people in the trash instead of the live ones.
+	Trashed bool
+
+	Sort []SortField
+
+	Limit  int
+	Offset int
+	// Cursor is the NextCursor of a previous page; rows up to and including it are skipped.
+	Cursor string
+}
+
+// PersonPage is one page of a person listing.
+type PersonPage struct {
+	Items      []Person `json:"items"`
+	Total      int      `json:"total"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// cursor is the decoded form of PersonPage.NextCursor. It holds the sort key of the last row on a page, so the next
+// page can continue after it regardless of inserts and deletes in between.
+type cursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func (r *Repository) List(ctx context.Context, filter ListFilter) (*PersonPage, error) {
+	conditions, args := filterConditions(filter)
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM people"+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count persons: %w", err)
+	}
+
+	limit := 0
+	if filter.Limit > 0 {
+		// One extra row tells us whether there is a next page.
+		limit = filter.Limit + 1
+	}
+	query, args, sort, err := selectQuery(filter, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve persons: %w", err)
+	}
+	defer rows.Close()
+
+	page := &PersonPage{Items: []Person{}, Total: total}
+	for rows.Next() {
+		var p Person
+		if err := scanPerson(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan person: %w", err)
+		}
+		page.Items = append(page.Items, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	if filter.Limit > 0 && len(page.Items) > filter.Limit {
+		page.Items = page.Items[:filter.Limit]
+		page.NextCursor = encodeCursor(sort, page.Items[len(page.Items)-1])
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Listed persons", zap.Int("count", len(page.Items)), zap.Int("total", total))
+	return page, nil
+}
+
+// Export calls fn for every person matching filter, in filter order, as rows arrive from the database, so the
+// result set is never held in memory. Iteration stops at the first error returned by fn.
+func (r *Repository) Export(ctx context.Context, filter ListFilter, fn func(Person) error) error {
+	query, args, _, err := selectQuery(filter, filter.Limit)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve persons: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var p Person
+		if err := scanPerson(rows, &p); err != nil {
+			return fmt.Errorf("failed to scan person: %w", err)
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Exported persons", zap.Int("count", count))
+	return nil
+}
+
+// selectQuery builds the SELECT for filter, including its cursor, order and offset. limit 0 means no LIMIT. The
+// returned sort is the effective order, which always ends with id.
+func selectQuery(filter ListFilter, limit int) (string, []interface{}, []SortField, error) {
+	conditions, args := filterConditions(filter)
+
+	// id is always the final sort key so that the order, and therefore the cursor, is unambiguous.
+	sort := append([]SortField{}, filter.Sort...)
+	hasID := false
+	for _, f := range sort {
+		hasID = hasID || f.Column == "id"
+	}
+	if !hasID {
+		sort = append(sort, SortField{Column: "id"})
+	}
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor, sort)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		condition, cursorArgs := keysetCondition(sort, c, len(args)+1)
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	orderBy := make([]string, len(sort))
+	for i, f := range sort {
+		orderBy[i] = sortExpr(f.Column)
+		if f.Desc {
+			orderBy[i] += " DESC"
+		}
+	}
+
+	query := `
+		SELECT ` + personColumns + `
+		FROM people` + where + " ORDER BY " + strings.Join(orderBy, ", ")
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", filter.Offset)
+	}
+
+	return query, args, sort, nil
+}
+
+func filterConditions(filter ListFilter) ([]string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	if filter.Trashed {
+		conditions[0] = "deleted_at IS NOT NULL"
+	}
+	args := []interface{}{}
+
+	add := func(format string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	for _, f := range []struct{ column, value string }{
+		{"name", filter.Name},
+		{"surname", filter.Surname},
+		{"patronymic", filter.Patronymic},
+	} {
+		switch {
+		case f.value == "":
+		case filter.Exact:
+			add(f.column+" = $%d", f.value)
+		default:
+			add(f.column+" ILIKE $%d", likePrefix(f.value))
+		}
+	}
+	if filter.Age != nil {
+		add("age = $%d", *filter.Age)
+	}
+	if filter.AgeGTE != nil {
+		add("age >= $%d", *filter.AgeGTE)
+	}
+	if filter.AgeLTE != nil {
+		add("age <= $%d", *filter.AgeLTE)
+	}
+	if len(filter.Genders) > 0 {
+		add("gender = ANY($%d)", filter.Genders)
+	}
+	if len(filter.Nationalities) > 0 {
+		add("nationality = ANY($%d)", filter.Nationalities)
+	}
+
+	return conditions, args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePrefix escapes LIKE wildcards in s and turns it into a prefix pattern.
+func likePrefix(s string) string {
+	return likeEscaper.Replace(s) + "%"
+}
+
+// keysetCondition builds the row comparison "sort key > cursor" for a mixed-direction ORDER BY, e.g.
+// (age < $1) OR (age = $1 AND id > $2) for "-age,id".
+func keysetCondition(sort []SortField, c cursor, argIndex int) (string, []interface{}) {
+	var args []interface{}
+	var alternatives []string
+	for i, f := range sort {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("%s = $%d", sortExpr(sort[j].Column), argIndex+j))
+		}
+		op := ">"
+		if f.Desc {
+			op = "<"
+		}
+		terms = append(terms, fmt.Sprintf("%s %s $%d", sortExpr(f.Column), op, argIndex+i))
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+		args = append(args, c.Values[i])
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+func sortSpec(sort []SortField) string {
+	parts := make([]string, len(sort))
+	for i, f := range sort {
+		parts[i] = f.Column
+		if f.Desc {
+			parts[i] = "-" + parts[i]
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// sortExpr is the ORDER BY expression for a column. Nullable columns are coalesced so that keyset comparisons
+// against a cursor never see NULL; nulls sort before every other value.
+func sortExpr(column string) string {
+	switch column {
+	case "age":
+		return "COALESCE(age, -1)"
+	case "patronymic", "gender", "nationality":
+		return "COALESCE(" + column + ", '')"
+	}
+	return column
+}
+
+// columnValue returns the value of sortExpr(column) for p.
+func columnValue(p Person, column string) interface{} {
+	switch column {
+	case "id":
+		return p.ID
+	case "name":
+		return p.Name
+	case "surname":
+		return p.Surname
+	case "patronymic":
+		return p.Patronymic
+	case "age":
+		if p.Age == nil {
+			return -1
+		}
+		return *p.Age
+	case "gender":
+		return stringValue(p.Gender)
+	case "nationality":
+		return stringValue(p.Nationality)
+	}
+	return nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func encodeCursor(sort []SortField, last Person) string {
+	c := cursor{Sort: sortSpec(sort)}
+	for _, f := range sort {
+		c.Values = append(c.Values, columnValue(last, f.Column))
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, sort []SortField) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	if c.Sort != sortSpec(sort) || len(c.Values) != len(sort) {
+		return c, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidCursor)
+	}
+
+	// JSON numbers decode as float64; integer columns need them back as ints.
+	for i, f := range sort {
+		switch f.Column {
+		case "id", "age":
+			n, ok := c.Values[i].(float64)
+			if !ok || n != math.Trunc(n) {
+				return c, ErrInvalidCursor
+			}
+			c.Values[i] = int(n)
+		default:
+			if _, ok := c.Values[i].(string); !ok {
+				return c, ErrInvalidCursor
+			}
+		}
+	}
+	return c, nil
+}
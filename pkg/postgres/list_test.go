@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []SortField
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "age", want: []SortField{{Column: "age"}}},
+		{in: "-age,surname", want: []SortField{{Column: "age", Desc: true}, {Column: "surname"}}},
+		{in: " +name , -id ", want: []SortField{{Column: "name"}, {Column: "id", Desc: true}}},
+		{in: "age,,name", want: []SortField{{Column: "age"}, {Column: "name"}}},
+		{in: "password", wantErr: true},
+		{in: "age,-age", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSort(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSort(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSort(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		sort     []SortField
+		values   []interface{}
+		argIndex int
+		want     string
+	}{
+		{
+			name:     "single ascending",
+			sort:     []SortField{{Column: "id"}},
+			values:   []interface{}{7},
+			argIndex: 1,
+			want:     "((id > $1))",
+		},
+		{
+			name:     "mixed directions",
+			sort:     []SortField{{Column: "age", Desc: true}, {Column: "id"}},
+			values:   []interface{}{30, 7},
+			argIndex: 1,
+			want:     "((COALESCE(age, -1) < $1) OR (COALESCE(age, -1) = $1 AND id > $2))",
+		},
+		{
+			name:     "three keys after filter arguments",
+			sort:     []SortField{{Column: "surname"}, {Column: "gender", Desc: true}, {Column: "id"}},
+			values:   []interface{}{"Petrov", "m", 7},
+			argIndex: 3,
+			want: "((surname > $3) OR (surname = $3 AND COALESCE(gender, '') < $4) OR " +
+				"(surname = $3 AND COALESCE(gender, '') = $4 AND id > $5))",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args := keysetCondition(tt.sort, cursor{Values: tt.values}, tt.argIndex)
+			if got != tt.want {
+				t.Errorf("condition = %s, want %s", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.values) {
+				t.Errorf("args = %v, want %v", args, tt.values)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	age := 30
+	gender := "m"
+	person := Person{ID: 7, Name: "Ivan", Surname: "Petrov", Age: &age, Gender: &gender}
+
+	tests := []struct {
+		name string
+		sort []SortField
+		want []interface{}
+	}{
+		{name: "id", sort: []SortField{{Column: "id"}}, want: []interface{}{7}},
+		{name: "age desc", sort: []SortField{{Column: "age", Desc: true}, {Column: "id"}}, want: []interface{}{30, 7}},
+		{name: "strings", sort: []SortField{{Column: "surname"}, {Column: "gender"}, {Column: "id"}}, want: []interface{}{"Petrov", "m", 7}},
+		{name: "nulls", sort: []SortField{{Column: "nationality"}, {Column: "patronymic"}, {Column: "id"}}, want: []interface{}{"", "", 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := decodeCursor(encodeCursor(tt.sort, person), tt.sort)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if !reflect.DeepEqual(c.Values, tt.want) {
+				t.Errorf("values = %#v, want %#v", c.Values, tt.want)
+			}
+		})
+	}
+
+	t.Run("null age", func(t *testing.T) {
+		sort := []SortField{{Column: "age"}, {Column: "id"}}
+		c, err := decodeCursor(encodeCursor(sort, Person{ID: 1}), sort)
+		if err != nil {
+			t.Fatalf("decodeCursor: %v", err)
+		}
+		if want := []interface{}{-1, 1}; !reflect.DeepEqual(c.Values, want) {
+			t.Errorf("values = %#v, want %#v", c.Values, want)
+		}
+	})
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	byAge := []SortField{{Column: "age"}, {Column: "id"}}
+	byID := []SortField{{Column: "id"}}
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   []SortField
+	}{
+		{name: "not base64", cursor: "!!!", sort: byID},
+		{name: "not JSON", cursor: base64.RawURLEncoding.EncodeToString([]byte("{")), sort: byID},
+		{name: "other sort order", cursor: encodeCursor(byAge, Person{ID: 1}), sort: byID},
+		{name: "wrong value count", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"id","v":[1,2]}`)), sort: byID},
+		{name: "non-numeric id", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"id","v":["x"]}`)), sort: byID},
+		{name: "fractional id", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"id","v":[1.5]}`)), sort: byID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor, tt.sort); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeCursor error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"TestRest/pkg/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"strings"
+)
+
+// PatchField is one member of a JSON Merge Patch (RFC 7396). A field that is not Set leaves its column unchanged;
+// a Set field with a nil Value clears it.
+type PatchField[T any] struct {
+	Set   bool
+	Value *T
+}
+
+// UnmarshalJSON is only called for members present in the document, which is what marks the field as Set.
+func (f *PatchField[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		f.Value = nil
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	f.Value = &v
+	return nil
+}
+
+// PersonPatch lists the columns of a person to change.
+type PersonPatch struct {
+	Name        PatchField[string]
+	Surname     PatchField[string]
+	Patronymic  PatchField[string]
+	Age         PatchField[int]
+	Gender      PatchField[string]
+	Nationality PatchField[string]
+}
+
+// Empty reports whether the patch changes nothing.
+func (p PersonPatch) Empty() bool {
+	return !p.Name.Set && !p.Surname.Set && !p.Patronymic.Set && !p.Age.Set && !p.Gender.Set && !p.Nationality.Set
+}
+
+// Patch changes only the columns set in patch with a single UPDATE, so concurrent writes to other columns are kept.
+// A null or empty patronymic is stored as NULL.
+func (r *Repository) Patch(ctx context.Context, id, version int, patch PersonPatch) (*Person, error) {
+	if patch.Empty() {
+		person, err := r.Get(ctx, id)
+		if err == nil && version != 0 && person.Version != version {
+			return nil, ErrVersionMismatch
+		}
+		return person, err
+	}
+
+	var assignments []string
+	var args []interface{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if patch.Name.Set {
+		set("name", patch.Name.Value)
+	}
+	if patch.Surname.Set {
+		set("surname", patch.Surname.Value)
+	}
+	if patch.Patronymic.Set {
+		var patronymic *string
+		if patch.Patronymic.Value != nil && *patch.Patronymic.Value != "" {
+			patronymic = patch.Patronymic.Value
+		}
+		set("patronymic", patronymic)
+	}
+	if patch.Age.Set {
+		set("age", patch.Age.Value)
+	}
+	if patch.Gender.Set {
+		set("gender", patch.Gender.Value)
+	}
+	if patch.Nationality.Set {
+		set("nationality", patch.Nationality.Value)
+	}
+
+	assignments = append(assignments, "version = version + 1")
+
+	args = append(args, id, version)
+	query := `
+		UPDATE people
+		SET ` + strings.Join(assignments, ", ") + fmt.Sprintf(`
+		WHERE id = $%d AND ($%d = 0 OR version = $%d)
+		RETURNING `, len(args)-1, len(args), len(args)) + personColumns
+
+	person, err := r.audited(ctx, OperationUpdate, id, false, func(tx pgx.Tx) (*Person, error) {
+		var person Person
+		err := scanPerson(tx.QueryRow(ctx, query, args...), &person)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, classify("failed to patch person", err)
+		}
+		return &person, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Patched person", zap.Int("id", person.ID), zap.Int("columns", len(assignments)-1), zap.Int("version", person.Version))
+	return person, nil
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPatchFieldUnmarshalJSON(t *testing.T) {
+	type document struct {
+		Patronymic PatchField[string] `json:"patronymic"`
+		Age        PatchField[int]    `json:"age"`
+	}
+	str := func(s string) *string { return &s }
+	num := func(n int) *int { return &n }
+
+	tests := []struct {
+		name    string
+		in      string
+		want    document
+		wantErr bool
+	}{
+		{name: "absent", in: `{}`, want: document{}},
+		{name: "null", in: `{"patronymic": null, "age": null}`, want: document{Patronymic: PatchField[string]{Set: true}, Age: PatchField[int]{Set: true}}},
+		{name: "value", in: `{"patronymic": "Ivanovich", "age": 30}`, want: document{Patronymic: PatchField[string]{Set: true, Value: str("Ivanovich")}, Age: PatchField[int]{Set: true, Value: num(30)}}},
+		{name: "empty string", in: `{"patronymic": ""}`, want: document{Patronymic: PatchField[string]{Set: true, Value: str("")}}},
+		{name: "zero", in: `{"age": 0}`, want: document{Age: PatchField[int]{Set: true, Value: num(0)}}},
+		{name: "wrong type", in: `{"age": "30"}`, wantErr: true},
+		{name: "fraction", in: `{"age": 30.5}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got document
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal(%s) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPersonPatchEmpty(t *testing.T) {
+	if !(PersonPatch{}).Empty() {
+		t.Error("zero patch is not empty")
+	}
+	// Clearing a field is a change.
+	if (PersonPatch{Nationality: PatchField[string]{Set: true}}).Empty() {
+		t.Error("patch clearing nationality is empty")
+	}
+}
@@ -0,0 +1,265 @@
+package postgres
+
+import (
+	"TestRest/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Person is a row of the "people" table. Age, Nationality and Gender are nil when enrichment could not provide them.
+// A missing patronymic is stored as NULL and represented by an empty Patronymic. Version starts at 1 and is
+// incremented by every update. DeletedAt is set while the person is in the trash.
+type Person struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Surname     string     `json:"surname"`
+	Patronymic  string     `json:"patronymic"`
+	Age         *int       `json:"age"`
+	Nationality *string    `json:"nationality"`
+	Gender      *string    `json:"gender"`
+	Version     int        `json:"version"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+// personColumns are the columns read by scanPerson, in order.
+const personColumns = "id, name, surname, patronymic, age, nationality, gender, version, deleted_at"
+
+func scanPerson(row pgx.Row, p *Person) error {
+	var patronymic *string
+	if err := row.Scan(&p.ID, &p.Name, &p.Surname, &patronymic, &p.Age, &p.Nationality, &p.Gender, &p.Version, &p.DeletedAt); err != nil {
+		return err
+	}
+	p.Patronymic = stringValue(patronymic)
+	return nil
+}
+
+// PeopleRepository is the storage of the "people" table used by the HTTP handlers. Writes to an existing person take
+// the version the caller last saw and fail with ErrVersionMismatch if it changed since; version 0 skips the check.
+// Deleted people move to the trash, where only List with ListFilter.Trashed and Restore can see them, until Purge
+// removes them for good. Every write is recorded in person_audit, attributed to the AuditInfo of its context, and
+// as a new row of people_versions.
+type PeopleRepository interface {
+	Create(ctx context.Context, p Person) (*Person, error)
+	CreateMany(ctx context.Context, people []Person) (int64, error)
+	Get(ctx context.Context, id int) (*Person, error)
+	List(ctx context.Context, filter ListFilter) (*PersonPage, error)
+	Export(ctx context.Context, filter ListFilter, fn func(Person) error) error
+	Update(ctx context.Context, p Person) (*Person, error)
+	Patch(ctx context.Context, id, version int, patch PersonPatch) (*Person, error)
+	Delete(ctx context.Context, id, version int) error
+	Restore(ctx context.Context, id, version int) (*Person, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	History(ctx context.Context, id int) ([]AuditEntry, error)
+	GetAsOf(ctx context.Context, id int, at time.Time) (*Person, error)
+	Revert(ctx context.Context, id, version, toVersion int) (*Person, error)
+}
+
+// Repository is the pgx implementation of PeopleRepository.
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+var _ PeopleRepository = (*Repository)(nil)
+
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(ctx context.Context, p Person) (*Person, error) {
+	var person Person
+	query := `
+		INSERT INTO people (name, surname, patronymic, age, nationality, gender)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+		RETURNING ` + personColumns
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if err := scanPerson(tx.QueryRow(ctx, query, p.Name, p.Surname, p.Patronymic, p.Age, p.Nationality, p.Gender), &person); err != nil {
+			return classify("failed to insert and retrieve person", err)
+		}
+		if err := audit(ctx, tx, OperationInsert, person.ID, nil); err != nil {
+			return err
+		}
+		return recordVersion(ctx, tx, person.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Inserted person", zap.Int("id", person.ID))
+	return &person, nil
+}
+
+// CreateMany inserts people with a single COPY. Either all of them are inserted or none. The rows are copied into a
+// temporary table first so that they can be inserted and audited with one statement.
+func (r *Repository) CreateMany(ctx context.Context, people []Person) (int64, error) {
+	var count int64
+	err := pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			CREATE TEMPORARY TABLE people_import (
+				name VARCHAR(100), surname VARCHAR(100), patronymic VARCHAR(100),
+				age INT, nationality VARCHAR(100), gender CHAR(1)
+			) ON COMMIT DROP
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to create import table: %w", err)
+		}
+
+		_, err = tx.CopyFrom(ctx,
+			pgx.Identifier{"people_import"},
+			[]string{"name", "surname", "patronymic", "age", "nationality", "gender"},
+			pgx.CopyFromSlice(len(people), func(i int) ([]interface{}, error) {
+				p := people[i]
+				return []interface{}{p.Name, p.Surname, p.Patronymic, p.Age, p.Nationality, p.Gender}, nil
+			}),
+		)
+		if err != nil {
+			return classify("failed to copy persons", err)
+		}
+
+		info := auditInfo(ctx)
+		tag, err := tx.Exec(ctx, `
+			WITH inserted AS (
+				INSERT INTO people (name, surname, patronymic, age, nationality, gender)
+				SELECT name, surname, NULLIF(patronymic, ''), age, nationality, gender FROM people_import
+				RETURNING *
+			),
+			versioned AS (
+				INSERT INTO people_versions (person_id, version, name, surname, patronymic, age, nationality, gender, deleted_at, valid_from)
+				SELECT id, version, name, surname, patronymic, age, nationality, gender, deleted_at, statement_timestamp()
+				FROM inserted
+			)
+			INSERT INTO person_audit (person_id, operation, actor, request_id, new_row)
+			SELECT id, $1, $2, $3, to_jsonb(inserted) FROM inserted
+		`, OperationInsert, nullString(info.Actor), nullString(info.RequestID))
+		if err != nil {
+			return classify("failed to insert persons", err)
+		}
+		count = tag.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Inserted persons", zap.Int64("count", count))
+	return count, nil
+}
+
+func (r *Repository) Get(ctx context.Context, id int) (*Person, error) {
+	query := `
+		SELECT ` + personColumns + `
+		FROM people
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	var p Person
+	err := scanPerson(r.db.QueryRow(ctx, query, id), &p)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve person: %w", err)
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Retrieved person", zap.Int("id", p.ID))
+	return &p, nil
+}
+
+// Update replaces the editable columns of person p.ID. A non-zero p.Version is the version the caller expects the
+// row to have; the row is only changed if it still does.
+func (r *Repository) Update(ctx context.Context, p Person) (*Person, error) {
+	query := `
+		UPDATE people
+		SET name = $1, surname = $2, patronymic = NULLIF($3, ''), age = $4, gender = $5, nationality = $6, version = version + 1
+		WHERE id = $7 AND ($8 = 0 OR version = $8)
+		RETURNING ` + personColumns
+	person, err := r.audited(ctx, OperationUpdate, p.ID, false, func(tx pgx.Tx) (*Person, error) {
+		var person Person
+		err := scanPerson(tx.QueryRow(ctx, query, p.Name, p.Surname, p.Patronymic, p.Age, p.Gender, p.Nationality, p.ID, p.Version), &person)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, classify("failed to update person", err)
+		}
+		return &person, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Updated person", zap.Int("id", person.ID), zap.Int("version", person.Version))
+	return person, nil
+}
+
+// Delete moves a person to the trash.
+func (r *Repository) Delete(ctx context.Context, id, version int) error {
+	query := `
+		UPDATE people
+		SET deleted_at = now(), version = version + 1
+		WHERE id = $1 AND ($2 = 0 OR version = $2)
+		RETURNING ` + personColumns
+	_, err := r.audited(ctx, OperationDelete, id, false, func(tx pgx.Tx) (*Person, error) {
+		var person Person
+		err := scanPerson(tx.QueryRow(ctx, query, id, version), &person)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to delete person: %w", err)
+		}
+		return &person, err
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Deleted person", zap.Int("id", id))
+	return nil
+}
+
+// Restore takes a person out of the trash.
+func (r *Repository) Restore(ctx context.Context, id, version int) (*Person, error) {
+	query := `
+		UPDATE people
+		SET deleted_at = NULL, version = version + 1
+		WHERE id = $1 AND ($2 = 0 OR version = $2)
+		RETURNING ` + personColumns
+	person, err := r.audited(ctx, OperationRestore, id, true, func(tx pgx.Tx) (*Person, error) {
+		var person Person
+		err := scanPerson(tx.QueryRow(ctx, query, id, version), &person)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to restore person: %w", err)
+		}
+		return &person, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Restored person", zap.Int("id", id))
+	return person, nil
+}
+
+// Purge permanently removes the people that were moved to the trash before deletedBefore.
+func (r *Repository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	info := auditInfo(ctx)
+	tag, err := r.db.Exec(ctx, `
+		WITH purged AS (
+			DELETE FROM people
+			WHERE deleted_at < $1
+			RETURNING *
+		),
+		closed AS (
+			UPDATE people_versions v
+			SET valid_to = statement_timestamp()
+			FROM purged
+			WHERE v.person_id = purged.id AND v.valid_to IS NULL
+		)
+		INSERT INTO person_audit (person_id, operation, actor, request_id, old_row)
+		SELECT id, $2, $3, $4, to_jsonb(purged) FROM purged
+	`, deletedBefore, OperationPurge, nullString(info.Actor), nullString(info.RequestID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge persons: %w", err)
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Purged persons", zap.Int64("count", tag.RowsAffected()), zap.Time("deleted_before", deletedBefore))
+	return tag.RowsAffected(), nil
+}
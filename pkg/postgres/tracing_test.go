@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"TestRest/internal/tracing/tracingtest"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"strings"
+	"testing"
+)
+
+func TestQueryTracer(t *testing.T) {
+	exporter, _ := tracingtest.NewProvider()
+
+	tests := []struct {
+		name     string
+		sql      string
+		err      error
+		wantName string
+		wantCode codes.Code
+	}{
+		{name: "select", sql: "\n\tSELECT id FROM people WHERE id = $1", wantName: "postgres SELECT", wantCode: codes.Unset},
+		{name: "with", sql: "WITH(SELECT 1) SELECT 2", wantName: "postgres WITH", wantCode: codes.Unset},
+		{name: "failed", sql: "update people SET age = $1", err: errors.New("deadlock detected"), wantName: "postgres UPDATE", wantCode: codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+			ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+			tracer := queryTracer{}
+			queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: tt.sql, Args: []any{"Ivan"}})
+			tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 1"), Err: tt.err})
+			parent.End()
+
+			spans := exporter.GetSpans().Snapshots()
+			if len(spans) != 2 {
+				t.Fatalf("recorded %d spans, want the query and its parent", len(spans))
+			}
+			query := spans[0]
+			if query.Name() != tt.wantName {
+				t.Errorf("span name = %q, want %q", query.Name(), tt.wantName)
+			}
+			if query.Parent().SpanID() != parent.SpanContext().SpanID() {
+				t.Errorf("query span is not a child of the request span")
+			}
+			if query.Status().Code != tt.wantCode {
+				t.Errorf("span status = %s, want %s", query.Status().Code, tt.wantCode)
+			}
+			// Only the SQL text is recorded, never the arguments.
+			for _, kv := range query.Attributes() {
+				if kv.Value.Emit() == "Ivan" {
+					t.Errorf("argument recorded as %s", kv.Key)
+				}
+			}
+			want := map[attribute.Key]string{"db.statement": strings.TrimSpace(tt.sql), "db.rows_affected": "1"}
+			for _, kv := range query.Attributes() {
+				if w, ok := want[kv.Key]; ok && kv.Value.Emit() != w {
+					t.Errorf("%s = %q, want %q", kv.Key, kv.Value.Emit(), w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"TestRest/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"time"
+)
+
+// The people_versions table keeps every version of every person with the interval [valid_from, valid_to) during
+// which it was current; the current version has no valid_to. Trashed versions are kept too, with deleted_at set.
+
+// versionColumns selects a people_versions row in the order of personColumns.
+const versionColumns = "person_id, name, surname, patronymic, age, nationality, gender, version, deleted_at"
+
+// recordVersion closes the current version of person id and opens one for its current row. It runs after the row
+// lock is taken, so statement_timestamp() is later than the previous version's and the intervals never overlap.
+func recordVersion(ctx context.Context, tx pgx.Tx, id int) error {
+	query := `
+		WITH closed AS (
+			UPDATE people_versions
+			SET valid_to = statement_timestamp()
+			WHERE person_id = $1 AND valid_to IS NULL
+		)
+		INSERT INTO people_versions (person_id, version, name, surname, patronymic, age, nationality, gender, deleted_at, valid_from)
+		SELECT id, version, name, surname, patronymic, age, nationality, gender, deleted_at, statement_timestamp()
+		FROM people
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to record person version: %w", err)
+	}
+	return nil
+}
+
+// GetAsOf returns person id as it was at the given instant. It yields ErrNotFound if the person did not exist then
+// or was in the trash.
+func (r *Repository) GetAsOf(ctx context.Context, id int, at time.Time) (*Person, error) {
+	query := `
+		SELECT ` + versionColumns + `
+		FROM people_versions
+		WHERE person_id = $1 AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2)
+	`
+	var p Person
+	err := scanPerson(r.db.QueryRow(ctx, query, id, at), &p)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && p.DeletedAt != nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve person version: %w", err)
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Retrieved person version", zap.Int("id", p.ID), zap.Int("version", p.Version), zap.Time("as_of", at))
+	return &p, nil
+}
+
+// Revert writes a new version of person id whose fields equal those of version toVersion. A non-zero version is
+// checked against the current one as in Update.
+func (r *Repository) Revert(ctx context.Context, id, version, toVersion int) (*Person, error) {
+	query := `
+		UPDATE people p
+		SET name = v.name, surname = v.surname, patronymic = v.patronymic, age = v.age,
+			nationality = v.nationality, gender = v.gender, version = p.version + 1
+		FROM people_versions v
+		WHERE p.id = $1 AND ($2 = 0 OR p.version = $2) AND v.person_id = p.id AND v.version = $3
+		RETURNING p.id, p.name, p.surname, p.patronymic, p.age, p.nationality, p.gender, p.version, p.deleted_at
+	`
+	person, err := r.audited(ctx, OperationRevert, id, false, func(tx pgx.Tx) (*Person, error) {
+		var exists bool
+		err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM people_versions WHERE person_id = $1 AND version = $2)", id, toVersion).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check person version: %w", err)
+		}
+		if !exists {
+			return nil, ErrVersionNotFound
+		}
+
+		var person Person
+		err = scanPerson(tx.QueryRow(ctx, query, id, version, toVersion), &person)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, classify("failed to revert person", err)
+		}
+		return &person, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.GetLoggerFromContext(ctx).Info(ctx, "Reverted person", zap.Int("id", id), zap.Int("to_version", toVersion), zap.Int("version", person.Version))
+	return person, nil
+}